@@ -4,42 +4,73 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/heptiolabs/healthcheck"
 	"github.com/jamieaitken/cgs/config"
+	"github.com/jamieaitken/cgs/internal/detached"
 	"github.com/jamieaitken/cgs/mysql"
+	"github.com/jamieaitken/cgs/postgres"
 	"github.com/jamieaitken/cgs/publisher"
 	"github.com/jamieaitken/cgs/redis"
 	"github.com/jamieaitken/cgs/router"
 	"github.com/jamieaitken/cgs/server"
+	"github.com/jamieaitken/cgs/stream"
 	"github.com/jamieaitken/cgs/subscriber"
 )
 
 var (
-	ErrInvalidRedisClient = errors.New("redis client not found for given key")
-	ErrInvalidMySQLClient = errors.New("mysql client not found for given key")
-	ErrInvalidPublisher   = errors.New("kafka publisher not found for given key")
-	ErrInvalidSubscriber  = errors.New("kafka subscriber not found for given key")
-	ErrRunFailed          = errors.New("run errored")
+	ErrInvalidRedisClient    = errors.New("redis client not found for given key")
+	ErrInvalidMySQLClient    = errors.New("mysql client not found for given key")
+	ErrInvalidPostgresClient = errors.New("postgres client not found for given key")
+	ErrInvalidPublisher      = errors.New("kafka publisher not found for given key")
+	ErrInvalidSubscriber     = errors.New("kafka subscriber not found for given key")
+	ErrInvalidRedisStream    = errors.New("redis stream consumer not found for given key")
+	ErrRunFailed             = errors.New("run errored")
+	ErrOptionFailed          = errors.New("failed to apply option")
+	ErrMissingRouter         = errors.New("router must be registered before server")
+	ErrShutdownFailed        = errors.New("shutdown errored")
+	ErrShuttingDown          = errors.New("application is shutting down")
+	ErrHookFailed            = errors.New("hook errored")
+)
+
+const (
+	defaultShutdownTimeout = time.Second * 30
+	defaultHookTimeout     = time.Second * 15
 )
 
 type Application struct {
-	logger      *zap.Logger
-	config      *config.Config
-	redis       map[string]*redis.Redis
-	mysql       map[string]*mysql.MySQL
-	publishers  map[string]*publisher.KafkaPublisher
-	subscribers map[string]*subscriber.KafkaSubscriber
-	server      *server.Server
-	router      *router.Router
-	health      healthcheck.Handler
-	mu          sync.Mutex
+	logger          *zap.Logger
+	config          *config.Config
+	redis           map[string]*redis.Redis
+	mysql           map[string]*mysql.MySQL
+	postgres        map[string]*postgres.Postgres
+	publishers      map[string]*publisher.KafkaPublisher
+	subscribers     map[string]*subscriber.KafkaSubscriber
+	streams         map[string]*stream.Consumer
+	server          *server.Server
+	router          *router.Router
+	health          healthcheck.Handler
+	dependencies    []dependency
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+	onStart         []Hook
+	onStop          []Hook
+	hookTimeout     time.Duration
+	stopRun         context.CancelFunc
+	shuttingDown    int32
+	shutdownOnce    sync.Once
+	shutdownErr     error
+	mu              sync.Mutex
 }
 
-type Option func(*Application)
+type Option func(*Application) error
 
 func New(opts ...Option) (*Application, error) {
 	l, err := zap.NewProduction()
@@ -48,23 +79,43 @@ func New(opts ...Option) (*Application, error) {
 	}
 
 	app := &Application{
-		logger:      l,
-		health:      healthcheck.NewHandler(),
-		redis:       make(map[string]*redis.Redis),
-		mysql:       make(map[string]*mysql.MySQL),
-		publishers:  make(map[string]*publisher.KafkaPublisher),
-		subscribers: make(map[string]*subscriber.KafkaSubscriber),
+		logger:          l,
+		health:          healthcheck.NewHandler(),
+		redis:           make(map[string]*redis.Redis),
+		mysql:           make(map[string]*mysql.MySQL),
+		postgres:        make(map[string]*postgres.Postgres),
+		publishers:      make(map[string]*publisher.KafkaPublisher),
+		subscribers:     make(map[string]*subscriber.KafkaSubscriber),
+		streams:         make(map[string]*stream.Consumer),
+		shutdownTimeout: defaultShutdownTimeout,
+		signals:         []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		hookTimeout:     defaultHookTimeout,
 	}
 
-	app.Add(opts...)
+	app.health.AddReadinessCheck("shutdown", app.shutdownCheck)
+
+	err = app.Add(opts...)
+	if err != nil {
+		return nil, err
+	}
 
 	return app, nil
 }
 
-func (a *Application) Add(opts ...Option) {
+// Add applies every given option, returning all of those which failed wrapped in ErrOptionFailed.
+func (a *Application) Add(opts ...Option) error {
+	var errs []error
+
 	for _, opt := range opts {
-		opt(a)
+		err := opt(a)
+		if err != nil {
+			a.logger.Error("failed to apply option", zap.Error(err))
+
+			errs = append(errs, err)
+		}
 	}
+
+	return newMultiError(ErrOptionFailed, errs)
 }
 
 func (a *Application) Server() *server.Server {
@@ -111,6 +162,20 @@ func (a *Application) MySQL(key string) (*mysql.MySQL, error) {
 	return c, nil
 }
 
+func (a *Application) Postgres(key string) (*postgres.Postgres, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c, ok := a.postgres[key]
+	if !ok {
+		a.logger.Error(fmt.Sprintf("failed to get postgres client for %s", key), zap.Error(ErrInvalidPostgresClient))
+
+		return nil, ErrInvalidPostgresClient
+	}
+
+	return c, nil
+}
+
 func (a *Application) Publisher(key string) (*publisher.KafkaPublisher, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -139,15 +204,106 @@ func (a *Application) Subscriber(key string) (*subscriber.KafkaSubscriber, error
 	return c, nil
 }
 
+// RedisStream returns the stream consumer registered under the given key, whose Run can be given to Application.Run.
+func (a *Application) RedisStream(key string) (*stream.Consumer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c, ok := a.streams[key]
+	if !ok {
+		a.logger.Error(fmt.Sprintf("failed to get redis stream consumer for %s", key), zap.Error(ErrInvalidRedisStream))
+
+		return nil, ErrInvalidRedisStream
+	}
+
+	return c, nil
+}
+
+// Run runs every OnStart hook in order before starting every given function concurrently. As soon as one of them
+// fails the context shared between them is cancelled, Run then waits for all of them to return before reporting
+// every error wrapped in ErrRunFailed.
+// Once the context is cancelled, one of the application's signals is received or every function has returned, the
+// application is shut down.
 func (a *Application) Run(ctx context.Context, fns ...func(ctx context.Context) error) error {
-	for _, fn := range fns {
-		err := fn(ctx)
-		if err != nil {
-			a.logger.Error("run error", zap.Error(err))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stopSignals := a.notifyOnSignal(ctx, cancel)
+	defer stopSignals()
+
+	g, runCtx := newGroup(detached.WithoutCancel(ctx))
+	defer g.cancel()
 
-			return fmt.Errorf("%s: %w", err, ErrRunFailed)
+	a.mu.Lock()
+	a.stopRun = g.cancel
+	a.mu.Unlock()
+
+	var errs []error
+
+	err := a.runHooks(ctx, stageStart, a.onStart)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, fn := range fns {
+			g.Go(runCtx, fn)
 		}
 	}
 
-	return nil
+	stopped := make(chan struct{})
+	shutdown := make(chan error, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-runCtx.Done():
+		case <-stopped:
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+		defer cancel()
+
+		shutdown <- a.Shutdown(shutdownCtx)
+	}()
+
+	errs = append(errs, g.Wait()...)
+	close(stopped)
+
+	err = <-shutdown
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, err := range errs {
+		a.logger.Error("run error", zap.Error(err))
+	}
+
+	return newMultiError(ErrRunFailed, errs)
+}
+
+// notifyOnSignal calls cancel once any of the application's signals is received. The returned func stops listening.
+func (a *Application) notifyOnSignal(ctx context.Context, cancel context.CancelFunc) func() {
+	if len(a.signals) == 0 {
+		return func() {}
+	}
+
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, a.signals...)
+
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-osSignals:
+			a.logger.Info(fmt.Sprintf("received signal %s", sig))
+
+			cancel()
+		case <-ctx.Done():
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(osSignals)
+		close(done)
+	}
 }
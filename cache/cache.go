@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/jamieaitken/cgs/internal/detached"
+	"github.com/jamieaitken/cgs/redis"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultBeta        = 1.0
+	defaultLoadTimeout = time.Second * 10
+
+	// headerLength is the length of the header preceding every value held by Redis: a flag byte, followed by when
+	// the entry expires and how long it took to load, both in milliseconds.
+	headerLength  = 17
+	flagNotFound  = 1
+	flagsOffset   = 0
+	expiresOffset = 1
+	deltaOffset   = 9
+)
+
+var (
+	// ErrNotFound is returned by a loader should the value not exist, and by GetOrLoad should it be cached as such.
+	ErrNotFound     = errors.New("value not found")
+	ErrInvalidEntry = errors.New("cached entry is malformed")
+)
+
+// Loader loads the value of a key on a cache miss, returning ErrNotFound should it not exist.
+type Loader func(ctx context.Context) (interface{}, error)
+
+// Cache is a read-through cache held by Redis. Each key is loaded at most once at a time within the process, however
+// many lookups miss it at once, and is refreshed a little ahead of its expiry, at random, so that replicas do not all
+// miss it at the same moment.
+type Cache struct {
+	r           *redis.Redis
+	name        string
+	codec       Codec
+	negativeTTL time.Duration
+	beta        float64
+	loadTimeout time.Duration
+	group       singleflight.Group
+}
+
+type Option func(*Cache)
+
+// New returns a cache held by the given Redis. Its keys are namespaced by the given name, which labels its metrics.
+func New(r *redis.Redis, name string, opts ...Option) *Cache {
+	c := &Cache{
+		r:           r,
+		name:        name,
+		codec:       JSON,
+		beta:        defaultBeta,
+		loadTimeout: defaultLoadTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// entry is a value held by Redis, alongside what is needed to decide whether to refresh it early.
+type entry struct {
+	notFound bool
+	expires  time.Time
+	delta    time.Duration
+	value    []byte
+}
+
+// GetOrLoad decodes the value of the given key into value, a pointer, loading and caching it for ttl should it not be
+// cached. Should Redis be unreachable, the value is loaded regardless. A load is shared by every lookup of the key, so
+// it is not cancelled with the context of any one of them, running for up to the duration given by WithLoadTimeout
+// instead, whilst each lookup stops waiting on it once its own context is done.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, value interface{}, loader Loader) error {
+	k := c.key(key)
+
+	var e entry
+
+	b, err := c.r.Client().Get(ctx, k, c.name).Bytes()
+	if err == nil {
+		e, err = decodeEntry(b)
+	}
+
+	switch {
+	case err != nil:
+		missCount.WithLabelValues(c.name).Inc()
+	case c.refreshEarly(e):
+		refreshCount.WithLabelValues(c.name).Inc()
+	default:
+		hitCount.WithLabelValues(c.name).Inc()
+
+		return c.decode(e, value)
+	}
+
+	loaded := c.group.DoChan(k, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(detached.WithoutCancel(ctx), c.loadTimeout)
+		defer cancel()
+
+		return c.load(loadCtx, k, ttl, loader)
+	})
+
+	select {
+	case res := <-loaded:
+		if res.Err != nil {
+			return res.Err
+		}
+
+		return c.decode(res.Val.(entry), value)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Delete removes the given key, so that it is loaded afresh on its next lookup.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.r.Provider().Del(ctx, c.key(key)).Err()
+}
+
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, loader Loader) (entry, error) {
+	start := time.Now()
+
+	v, err := loader(ctx)
+	delta := time.Since(start)
+
+	loadDuration.WithLabelValues(c.name).Observe(delta.Seconds())
+
+	if errors.Is(err, ErrNotFound) {
+		notFoundCount.WithLabelValues(c.name).Inc()
+
+		if c.negativeTTL <= 0 {
+			return entry{}, err
+		}
+
+		e := entry{notFound: true, expires: time.Now().Add(c.negativeTTL), delta: delta}
+		c.set(ctx, key, e, c.negativeTTL)
+
+		return e, nil
+	}
+
+	if err != nil {
+		loadErrorCount.WithLabelValues(c.name).Inc()
+
+		return entry{}, err
+	}
+
+	b, err := c.codec.Marshal(v)
+	if err != nil {
+		loadErrorCount.WithLabelValues(c.name).Inc()
+
+		return entry{}, err
+	}
+
+	e := entry{expires: time.Now().Add(ttl), delta: delta, value: b}
+	c.set(ctx, key, e, ttl)
+
+	return e, nil
+}
+
+// set caches the given entry. Failing to do so is not reported, as the value loaded is returned regardless.
+func (c *Cache) set(ctx context.Context, key string, e entry, ttl time.Duration) {
+	c.r.Client().Set(ctx, key, encodeEntry(e), ttl, c.name)
+}
+
+// refreshEarly decides at random whether to refresh the entry ahead of its expiry, becoming ever more likely as it
+// nears, and the sooner the longer it takes to load. See "Optimal Probabilistic Cache Stampede Prevention", Vattani et
+// al.
+func (c *Cache) refreshEarly(e entry) bool {
+	if c.beta <= 0 {
+		return false
+	}
+
+	gap := time.Duration(float64(e.delta) * c.beta * -math.Log(1-rand.Float64()))
+
+	return !time.Now().Add(gap).Before(e.expires)
+}
+
+func (c *Cache) decode(e entry, value interface{}) error {
+	if e.notFound {
+		return ErrNotFound
+	}
+
+	return c.codec.Unmarshal(e.value, value)
+}
+
+func (c *Cache) key(key string) string {
+	return fmt.Sprintf("cache:%s:%s", c.name, key)
+}
+
+func encodeEntry(e entry) []byte {
+	b := make([]byte, headerLength+len(e.value))
+
+	if e.notFound {
+		b[flagsOffset] = flagNotFound
+	}
+
+	binary.BigEndian.PutUint64(b[expiresOffset:], uint64(e.expires.UnixNano()/int64(time.Millisecond)))
+	binary.BigEndian.PutUint64(b[deltaOffset:], uint64(e.delta.Milliseconds()))
+	copy(b[headerLength:], e.value)
+
+	return b
+}
+
+func decodeEntry(b []byte) (entry, error) {
+	if len(b) < headerLength {
+		return entry{}, ErrInvalidEntry
+	}
+
+	expires := int64(binary.BigEndian.Uint64(b[expiresOffset:]))
+
+	return entry{
+		notFound: b[flagsOffset]&flagNotFound != 0,
+		expires:  time.Unix(0, expires*int64(time.Millisecond)),
+		delta:    time.Duration(binary.BigEndian.Uint64(b[deltaOffset:])) * time.Millisecond,
+		value:    b[headerLength:],
+	}, nil
+}
@@ -0,0 +1,316 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jamieaitken/cgs/cache"
+	"github.com/jamieaitken/cgs/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type book struct {
+	Title  string
+	Author string
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenCallers  int
+		givenLoader   func(calls *int32) cache.Loader
+		expectedBook  book
+		expectedCalls int32
+		expectedError error
+	}{
+		{
+			name:         "given concurrent lookups of a key, expect it loaded once and shared between them",
+			givenCallers: 10,
+			givenLoader: func(calls *int32) cache.Loader {
+				return func(ctx context.Context) (interface{}, error) {
+					atomic.AddInt32(calls, 1)
+					time.Sleep(time.Millisecond * 200)
+
+					return book{Title: "Dune", Author: "Frank Herbert"}, nil
+				}
+			},
+			expectedBook:  book{Title: "Dune", Author: "Frank Herbert"},
+			expectedCalls: 1,
+		},
+		{
+			name:         "given a loader which fails, expect its error returned",
+			givenCallers: 1,
+			givenLoader: func(calls *int32) cache.Loader {
+				return func(ctx context.Context) (interface{}, error) {
+					atomic.AddInt32(calls, 1)
+
+					return nil, cache.ErrNotFound
+				}
+			},
+			expectedCalls: 1,
+			expectedError: cache.ErrNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Redis is unreachable, so every lookup misses and is loaded.
+			r := redis.New([]string{"127.0.0.1:1"}, redis.WithClientType(redis.NonFailOver))
+
+			defer r.Close()
+
+			c := cache.New(r, "books")
+
+			var (
+				calls int32
+				wg    sync.WaitGroup
+			)
+
+			books := make([]book, test.givenCallers)
+			errs := make([]error, test.givenCallers)
+
+			for i := 0; i < test.givenCallers; i++ {
+				wg.Add(1)
+
+				go func(i int) {
+					defer wg.Done()
+
+					errs[i] = c.GetOrLoad(context.Background(), "dune", time.Minute, &books[i], test.givenLoader(&calls))
+				}(i)
+			}
+
+			wg.Wait()
+
+			if !cmp.Equal(atomic.LoadInt32(&calls), test.expectedCalls) {
+				t.Fatal(cmp.Diff(atomic.LoadInt32(&calls), test.expectedCalls))
+			}
+
+			for i := range books {
+				if !cmp.Equal(errs[i], test.expectedError, cmpopts.EquateErrors()) {
+					t.Fatal(cmp.Diff(errs[i], test.expectedError, cmpopts.EquateErrors()))
+				}
+
+				if test.expectedError == nil && !cmp.Equal(books[i], test.expectedBook) {
+					t.Fatal(cmp.Diff(books[i], test.expectedBook))
+				}
+			}
+		})
+	}
+}
+
+func TestCache_GetOrLoad_Cancelled(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenTimeout      time.Duration
+		expectedBook      book
+		expectedError     error
+		expectedCancelled error
+	}{
+		{
+			name:              "given the lookup which started a load is cancelled, expect the others to be loaded",
+			givenTimeout:      time.Second,
+			expectedBook:      book{Title: "Dune", Author: "Frank Herbert"},
+			expectedCancelled: context.Canceled,
+		},
+		{
+			name:              "given a load which outlasts the load timeout, expect every lookup to fail",
+			givenTimeout:      time.Millisecond * 100,
+			expectedError:     context.DeadlineExceeded,
+			expectedCancelled: context.Canceled,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Redis is unreachable, so every lookup misses at once and joins the load already under way.
+			r := redis.New([]string{"127.0.0.1:1"}, redis.WithClientType(redis.NonFailOver), redis.WithMaxRetries(-1))
+
+			defer r.Close()
+
+			c := cache.New(r, "books", cache.WithLoadTimeout(test.givenTimeout))
+
+			var (
+				calls int32
+				once  sync.Once
+			)
+
+			started := make(chan struct{})
+
+			loader := func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				once.Do(func() {
+					close(started)
+				})
+
+				select {
+				case <-time.After(time.Millisecond * 500):
+					return book{Title: "Dune", Author: "Frank Herbert"}, nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancelled := make(chan error)
+
+			go func() {
+				var b book
+
+				cancelled <- c.GetOrLoad(ctx, "dune", time.Minute, &b, loader)
+			}()
+
+			<-started
+
+			waited := make(chan error)
+
+			var actual book
+
+			go func() {
+				waited <- c.GetOrLoad(context.Background(), "dune", time.Minute, &actual, loader)
+			}()
+
+			cancel()
+
+			err := <-cancelled
+			if !cmp.Equal(err, test.expectedCancelled, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedCancelled, cmpopts.EquateErrors()))
+			}
+
+			err = <-waited
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			if !cmp.Equal(actual, test.expectedBook) {
+				t.Fatal(cmp.Diff(actual, test.expectedBook))
+			}
+
+			if !cmp.Equal(atomic.LoadInt32(&calls), int32(1)) {
+				t.Fatal(cmp.Diff(atomic.LoadInt32(&calls), int32(1)))
+			}
+		})
+	}
+}
+
+func TestCache_GetOrLoad_Metrics(t *testing.T) {
+	tests := []struct {
+		name            string
+		givenName       string
+		givenErr        error
+		expectedMetrics map[string]float64
+	}{
+		{
+			name:      "given a value not found, expect it counted as not found rather than as a failed load",
+			givenName: "metrics-not-found",
+			givenErr:  cache.ErrNotFound,
+			expectedMetrics: map[string]float64{
+				"cache_miss_total":       1,
+				"cache_not_found_total":  1,
+				"cache_load_error_total": 0,
+			},
+		},
+		{
+			name:      "given a loader which fails, expect it counted as a failed load",
+			givenName: "metrics-load-error",
+			givenErr:  errors.New("failed to load"),
+			expectedMetrics: map[string]float64{
+				"cache_miss_total":       1,
+				"cache_not_found_total":  0,
+				"cache_load_error_total": 1,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Redis is unreachable, so every lookup misses and is loaded.
+			r := redis.New([]string{"127.0.0.1:1"}, redis.WithClientType(redis.NonFailOver), redis.WithMaxRetries(-1))
+
+			defer r.Close()
+
+			c := cache.New(r, test.givenName)
+
+			before := make(map[string]float64, len(test.expectedMetrics))
+			for name := range test.expectedMetrics {
+				before[name] = gather(t, name, test.givenName)
+			}
+
+			var b book
+
+			_ = c.GetOrLoad(context.Background(), "dune", time.Minute, &b, func(ctx context.Context) (interface{}, error) {
+				return nil, test.givenErr
+			})
+
+			for name, expected := range test.expectedMetrics {
+				actual := gather(t, name, test.givenName) - before[name]
+				if !cmp.Equal(actual, expected) {
+					t.Fatalf("%s: %s", name, cmp.Diff(actual, expected))
+				}
+			}
+		})
+	}
+}
+
+func TestCodec(t *testing.T) {
+	tests := []struct {
+		name       string
+		givenCodec cache.Codec
+		givenBook  book
+	}{
+		{
+			name:       "given json, expect the value to survive a round trip",
+			givenCodec: cache.JSON,
+			givenBook:  book{Title: "Dune", Author: "Frank Herbert"},
+		},
+		{
+			name:       "given gob, expect the value to survive a round trip",
+			givenCodec: cache.Gob,
+			givenBook:  book{Title: "Dune", Author: "Frank Herbert"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := test.givenCodec.Marshal(test.givenBook)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			var actual book
+
+			err = test.givenCodec.Unmarshal(b, &actual)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if !cmp.Equal(actual, test.givenBook) {
+				t.Fatal(cmp.Diff(actual, test.givenBook))
+			}
+		})
+	}
+}
+
+func gather(t *testing.T, metric, name string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != metric {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == name {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,89 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var labels = []string{"name"}
+
+var (
+	hitCount       *prometheus.CounterVec
+	missCount      *prometheus.CounterVec
+	refreshCount   *prometheus.CounterVec
+	notFoundCount  *prometheus.CounterVec
+	loadErrorCount *prometheus.CounterVec
+	loadDuration   *prometheus.HistogramVec
+)
+
+func init() {
+	hitCount = withHits()
+	missCount = withMisses()
+	refreshCount = withRefreshes()
+	notFoundCount = withNotFound()
+	loadErrorCount = withLoadErrors()
+	loadDuration = withLoadDuration()
+}
+
+func withHits() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_hit_total",
+		Help: "The number of lookups served from the cache, including those cached as not found",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withMisses() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_miss_total",
+		Help: "The number of lookups which were not cached, excluding those refreshed early",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withRefreshes() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_early_refresh_total",
+		Help: "The number of lookups which refreshed a cached value ahead of its expiry",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withNotFound() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_not_found_total",
+		Help: "The number of loads which found no value, whether or not it is then cached as not found",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withLoadErrors() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_load_error_total",
+		Help: "The number of loads which have failed, excluding those which found no value",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withLoadDuration() *prometheus.HistogramVec {
+	d := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "cache_load_duration_seconds",
+		Help: "The amount of time loads take",
+	}, labels)
+
+	prometheus.MustRegister(d)
+
+	return d
+}
@@ -0,0 +1,34 @@
+package cache
+
+import "time"
+
+// WithCodec sets the codec values are encoded by. Defaults to JSON.
+func WithCodec(codec Codec) Option {
+	return func(cache *Cache) {
+		cache.codec = codec
+	}
+}
+
+// WithNegativeTTL caches ErrNotFound, as returned by a loader, for the given duration, so that keys which do not exist
+// are not loaded on every request. By default, ErrNotFound is not cached.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(cache *Cache) {
+		cache.negativeTTL = ttl
+	}
+}
+
+// WithLoadTimeout sets how long a load may take, as it is not cancelled along with the lookups waiting on it. Defaults
+// to 10s.
+func WithLoadTimeout(timeout time.Duration) Option {
+	return func(cache *Cache) {
+		cache.loadTimeout = timeout
+	}
+}
+
+// WithEarlyRefresh sets how eagerly entries are refreshed before they expire, with larger values refreshing earlier.
+// 0 disables early refresh. Defaults to 1.
+func WithEarlyRefresh(beta float64) Option {
+	return func(cache *Cache) {
+		cache.beta = beta
+	}
+}
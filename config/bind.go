@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	keyTag      = "mapstructure"
+	defaultTag  = "default"
+	validateTag = "validate"
+)
+
+var (
+	ErrInvalidConfig  = errors.New("config failed validation")
+	ErrInvalidBinding = errors.New("binding must be a non-nil pointer to a struct")
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// field is a leaf of a bound struct alongside the config key it is read from.
+type field struct {
+	key   string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// Bind unmarshals the config into the struct pointed to by v. Keys are read from each field's mapstructure tag,
+// falling back to the field's name, and nested structs are read from nested keys.
+//
+// A field's default tag is used whenever its key is not set, whilst its validate tag holds a comma separated list of
+// rules, each of which must pass:
+//
+//	required     the key must be set to a non-zero value
+//	min=n        numbers and durations must be at least n, strings and slices must have a length of at least n
+//	max=n        numbers and durations must be at most n, strings and slices must have a length of at most n
+//	oneof=a b c  the value must be one of those given
+//
+// Every invalid key is listed in a single error wrapping ErrInvalidConfig. Should the config be watched, every reload
+// is validated against v too, though v itself is only populated once.
+func (c *Config) Bind(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := bind(c.viper, c.enableEnvVars, c.sources, v)
+	if err != nil {
+		return err
+	}
+
+	c.bindings = append(c.bindings, v)
+
+	return nil
+}
+
+// bind unmarshals vpr into v, leaving the values of the given secret keys out of any validation error.
+func bind(vpr *viper.Viper, enableEnvVars bool, secrets map[string]Source, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidBinding
+	}
+
+	fields := collectFields("", rv.Elem())
+
+	for _, f := range fields {
+		if enableEnvVars {
+			err := vpr.BindEnv(f.key)
+			if err != nil {
+				return err
+			}
+		}
+
+		def, ok := f.tag.Lookup(defaultTag)
+		if ok {
+			vpr.SetDefault(f.key, def)
+		}
+	}
+
+	err := vpr.Unmarshal(v)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, ErrInvalidConfig)
+	}
+
+	var msgs []string
+
+	for _, f := range fields {
+		msgs = append(msgs, validate(f, secrets[f.key].Kind == SourceSecret)...)
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s: %w", strings.Join(msgs, "; "), ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+// newBindingOf returns a pointer to a new zero value of the struct v points to, so that a reload can be validated
+// without altering v.
+func newBindingOf(v interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(v).Elem()).Interface()
+}
+
+func collectFields(prefix string, v reflect.Value) []field {
+	var fields []field
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name, opts := parseKeyTag(sf)
+		if name == "-" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			if strings.Contains(opts, "squash") {
+				fields = append(fields, collectFields(prefix, fv)...)
+
+				continue
+			}
+
+			fields = append(fields, collectFields(key, fv)...)
+
+			continue
+		}
+
+		fields = append(fields, field{
+			key:   key,
+			value: fv,
+			tag:   sf.Tag,
+		})
+	}
+
+	return fields
+}
+
+func parseKeyTag(sf reflect.StructField) (name, opts string) {
+	tag := sf.Tag.Get(keyTag)
+
+	parts := strings.SplitN(tag, ",", 2)
+	if len(parts) == 2 {
+		opts = parts[1]
+	}
+
+	name = strings.ToLower(parts[0])
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+
+	return name, opts
+}
+
+func validate(f field, secret bool) []string {
+	rules := f.tag.Get(validateTag)
+	if rules == "" {
+		return nil
+	}
+
+	var msgs []string
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg := rule, ""
+
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) == 2 {
+			name, arg = parts[0], parts[1]
+		}
+
+		msg := checkRule(f.value, name, arg, secret)
+		if msg != "" {
+			msgs = append(msgs, fmt.Sprintf("%s %s", f.key, msg))
+		}
+	}
+
+	return msgs
+}
+
+func checkRule(v reflect.Value, rule, arg string, secret bool) string {
+	switch rule {
+	case "required":
+		if v.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		limit, err := parseLimit(v, arg)
+		if err != nil {
+			return fmt.Sprintf("has invalid %s rule %q", rule, arg)
+		}
+
+		n, ok := magnitude(v)
+		if !ok {
+			return fmt.Sprintf("does not support the %s rule", rule)
+		}
+
+		if rule == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s", arg)
+		}
+
+		if rule == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s", arg)
+		}
+	case "oneof":
+		actual := fmt.Sprint(v.Interface())
+
+		for _, allowed := range strings.Fields(arg) {
+			if actual == allowed {
+				return ""
+			}
+		}
+
+		if secret {
+			return fmt.Sprintf("must be one of [%s]", arg)
+		}
+
+		return fmt.Sprintf("must be one of [%s], got %q", arg, actual)
+	default:
+		return fmt.Sprintf("has unknown rule %q", rule)
+	}
+
+	return ""
+}
+
+// parseLimit reads the argument of a min or max rule, which is a duration such as 5s for time.Duration fields.
+func parseLimit(v reflect.Value, arg string) (float64, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(arg)
+
+		return float64(d), err
+	}
+
+	return strconv.ParseFloat(arg, 64)
+}
+
+// magnitude returns the value of numbers, and the length of strings, slices and maps.
+func magnitude(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	default:
+		return 0, false
+	}
+}
@@ -3,54 +3,132 @@ package config
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 var (
 	ErrFailedToLoadConfigFile = errors.New("failed to load given config file")
+	ErrFailedToWatch          = errors.New("failed to watch config")
+	ErrFailedToReadConfigDir  = errors.New("failed to read given config directory")
+	ErrFailedToBindFlags      = errors.New("failed to bind given flags")
 )
 
+// Config holds values loaded from layered sources. Each Config owns its own viper instance so that several may be used
+// at once. From lowest to highest precedence, values are read from:
+//
+//  1. defaults declared by bindings
+//  2. the base config file
+//  3. each file of the config directory, in alphabetical order
+//  4. the environment-specific overlay of the base file, i.e. config.production.yaml for config.yaml
+//  5. environment variables, named by upper-casing the key, replacing its dots with underscores and adding the prefix
+//  6. command-line flags which have been set, named by the key
+//
+// Secrets may be read from files, such as those of Kubernetes secret mounts. Whenever the environment variable of a
+// key matching a redacted pattern suffixed with _FILE is set, i.e. MYSQL_PASSWORD_FILE for mysql.password, the key is
+// read from the file it names as though it were part of the config files. Given an env prefix, such variables are read
+// even for keys which are not otherwise known, so that APP_MYSQL_PASSWORD_FILE supplies mysql.password. Any value of
+// the form file:///path/to/secret is replaced by the contents of that file, too. Surrounding whitespace is trimmed and
+// each such key is reported as secret by IsSecret and redacted by Redacted. Secret files are only read once unless
+// WithWatch is given, in which case the config is reloaded whenever one is rotated.
 type Config struct {
-	file          string
-	enableEnvVars bool
+	file             string
+	dir              string
+	environment      string
+	enableEnvVars    bool
+	envPrefix        string
+	envReplacer      *strings.Replacer
+	flags            *pflag.FlagSet
+	watch            bool
+	bindings         []interface{}
+	redactedPatterns []*regexp.Regexp
+	logger           *zap.Logger
+	subscribers      map[string][]ChangeFunc
+	stopWatching     func()
+	viper            *viper.Viper
+	sources          map[string]Source
+	mu               sync.RWMutex
+	reloadMu         sync.Mutex
 }
 
 type Option func(*Config)
 
 func New(opts ...Option) (*Config, error) {
 	c := &Config{
-		enableEnvVars: true,
-		file:          "",
+		enableEnvVars:    true,
+		file:             "",
+		envReplacer:      strings.NewReplacer(".", "_"),
+		redactedPatterns: []*regexp.Regexp{defaultRedactedPattern},
+		logger:           zap.NewNop(),
+		subscribers:      make(map[string][]ChangeFunc),
 	}
 
 	c.add(opts...)
 
-	if c.enableEnvVars {
-		viper.AutomaticEnv()
+	v, sources, err := c.load(c.bindings)
+	if err != nil {
+		return nil, err
 	}
 
-	if c.file == "" {
-		return c, nil
-	}
+	c.viper = v
+	c.sources = sources
 
-	err := readInConfig(c.file)
-	if err != nil {
-		return nil, err
+	if c.watch {
+		err = c.startWatching()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return c, nil
 }
 
-func readInConfig(file string) error {
-	viper.SetConfigFile(file)
+// load reads every source into a new viper instance, binding it into each of the given bindings.
+func (c *Config) load(bindings []interface{}) (*viper.Viper, map[string]Source, error) {
+	v := viper.New()
+
+	if c.enableEnvVars {
+		v.SetEnvPrefix(c.envPrefix)
+		v.SetEnvKeyReplacer(c.envReplacer)
+		v.AutomaticEnv()
+	}
+
+	files, err := c.layerFiles()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	err := viper.ReadInConfig()
+	fileSources, err := mergeFiles(v, files)
 	if err != nil {
-		return fmt.Errorf("%v: %w", err, ErrFailedToLoadConfigFile)
+		return nil, nil, err
 	}
 
-	return nil
+	if c.flags != nil {
+		err = v.BindPFlags(c.flags)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %w", err, ErrFailedToBindFlags)
+		}
+	}
+
+	secretSources, err := c.resolveSecrets(v, bindingKeys(bindings))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, b := range bindings {
+		err = bind(v, c.enableEnvVars, secretSources, b)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return v, c.resolveSources(v, fileSources, secretSources), nil
 }
 
 func (c *Config) add(opts ...Option) {
@@ -62,3 +140,77 @@ func (c *Config) add(opts ...Option) {
 func (c *Config) File() string {
 	return c.file
 }
+
+// FileUsed returns the path of the config file read, if any.
+func (c *Config) FileUsed() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.viper.ConfigFileUsed()
+}
+
+func (c *Config) IsSet(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.viper.IsSet(key)
+}
+
+func (c *Config) String(key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.viper.GetString(key)
+}
+
+func (c *Config) Int(key string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.viper.GetInt(key)
+}
+
+func (c *Config) Duration(key string) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.viper.GetDuration(key)
+}
+
+func (c *Config) StringSlice(key string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.viper.GetStringSlice(key)
+}
+
+// Sub returns the values nested under the given key as their own Config. Should there be none, the returned Config
+// is empty. The returned Config is not reloaded.
+func (c *Config) Sub(key string) *Config {
+	c.mu.RLock()
+	sub := c.viper.Sub(key)
+
+	prefix := strings.ToLower(key) + "."
+	sources := make(map[string]Source)
+
+	for k, s := range c.sources {
+		if strings.HasPrefix(k, prefix) {
+			sources[strings.TrimPrefix(k, prefix)] = s
+		}
+	}
+	c.mu.RUnlock()
+
+	if sub == nil {
+		sub = viper.New()
+	}
+
+	return &Config{
+		enableEnvVars:    c.enableEnvVars,
+		envReplacer:      c.envReplacer,
+		redactedPatterns: c.redactedPatterns,
+		logger:           c.logger,
+		subscribers:      make(map[string][]ChangeFunc),
+		viper:            sub,
+		sources:          sources,
+	}
+}
@@ -1,13 +1,18 @@
 package config_test
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jamieaitken/cgs/config"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 )
 
 func TestNew_WithFile_Success(t *testing.T) {
@@ -29,12 +34,12 @@ func TestNew_WithFile_Success(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := config.New(test.givenOpts...)
+			cfg, err := config.New(test.givenOpts...)
 			if err != nil {
 				t.Fatalf("expected nil, got %v", err)
 			}
 
-			actual := viper.GetString(test.givenVar)
+			actual := cfg.String(test.givenVar)
 
 			if !cmp.Equal(actual, test.expectedValue) {
 				t.Fatalf(cmp.Diff(actual, test.expectedValue))
@@ -103,12 +108,12 @@ func TestNew_WithEnvVar_Success(t *testing.T) {
 
 			t.Log(s)
 
-			_, err := config.New(test.givenOpts...)
+			cfg, err := config.New(test.givenOpts...)
 			if err != nil {
 				t.Fatalf("expected nil, got %v", err)
 			}
 
-			actual := viper.GetString(test.givenVar)
+			actual := cfg.String(test.givenVar)
 
 			if !cmp.Equal(actual, test.expectedValue) {
 				t.Fatalf(cmp.Diff(actual, test.expectedValue))
@@ -145,3 +150,742 @@ func TestNew_WithEnvVar_Fail(t *testing.T) {
 		})
 	}
 }
+
+type testBinding struct {
+	Name     string        `mapstructure:"name" validate:"required"`
+	Port     int           `mapstructure:"port" validate:"min=1024,max=65535"`
+	Timeout  time.Duration `mapstructure:"timeout" default:"5s" validate:"max=30s"`
+	Level    string        `mapstructure:"level" default:"info" validate:"oneof=debug info error"`
+	Retries  int           `mapstructure:"retries" default:"3"`
+	Database struct {
+		Addr string `mapstructure:"addr" validate:"required"`
+	} `mapstructure:"database"`
+}
+
+func TestConfig_Bind_Success(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenOpts     []config.Option
+		expectedValue testBinding
+	}{
+		{
+			name: "given a valid config file, expect values and defaults to be bound",
+			givenOpts: []config.Option{
+				config.WithConfigFile("bind.yaml"),
+				config.WithEnvVars(false),
+			},
+			expectedValue: testBinding{
+				Name:    "books",
+				Port:    3030,
+				Timeout: time.Second * 2,
+				Level:   "debug",
+				Retries: 3,
+				Database: struct {
+					Addr string `mapstructure:"addr" validate:"required"`
+				}{
+					Addr: "root:hunter@(localhost:3306)/books",
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual testBinding
+
+			_, err := config.New(append(test.givenOpts, config.WithBinding(&actual))...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if !cmp.Equal(actual, test.expectedValue) {
+				t.Fatalf(cmp.Diff(actual, test.expectedValue))
+			}
+		})
+	}
+}
+
+func TestConfig_Bind_Fail(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenOpts     []config.Option
+		givenBinding  interface{}
+		expectedValue error
+		expectedKeys  []string
+	}{
+		{
+			name: "given an invalid config file, expect every invalid key to be listed",
+			givenOpts: []config.Option{
+				config.WithConfigFile("invalid_bind.yaml"),
+				config.WithEnvVars(false),
+			},
+			givenBinding:  &testBinding{},
+			expectedValue: config.ErrInvalidConfig,
+			expectedKeys:  []string{"name", "port", "timeout", "level", "database.addr"},
+		},
+		{
+			name: "given a binding which is not a pointer to a struct, expect error to be returned",
+			givenOpts: []config.Option{
+				config.WithEnvVars(false),
+			},
+			givenBinding:  testBinding{},
+			expectedValue: config.ErrInvalidBinding,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := config.New(append(test.givenOpts, config.WithBinding(test.givenBinding))...)
+			if err == nil {
+				t.Fatalf("expected %v, got nil", test.expectedValue)
+			}
+
+			if !cmp.Equal(err, test.expectedValue, cmpopts.EquateErrors()) {
+				t.Fatalf(cmp.Diff(err, test.expectedValue, cmpopts.EquateErrors()))
+			}
+
+			for _, key := range test.expectedKeys {
+				if !strings.Contains(err.Error(), key+" ") {
+					t.Fatalf("expected %s to be listed in %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Getters(t *testing.T) {
+	tests := []struct {
+		name                string
+		givenOpts           []config.Option
+		expectedString      string
+		expectedInt         int
+		expectedDuration    time.Duration
+		expectedStringSlice []string
+		expectedSubString   string
+	}{
+		{
+			name: "given a config file, expect typed values to be returned",
+			givenOpts: []config.Option{
+				config.WithConfigFile("bind.yaml"),
+				config.WithEnvVars(false),
+			},
+			expectedString:      "books",
+			expectedInt:         3030,
+			expectedDuration:    time.Second * 2,
+			expectedStringSlice: []string{"debug"},
+			expectedSubString:   "root:hunter@(localhost:3306)/books",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := config.New(test.givenOpts...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if !cmp.Equal(cfg.String("name"), test.expectedString) {
+				t.Fatalf(cmp.Diff(cfg.String("name"), test.expectedString))
+			}
+
+			if !cmp.Equal(cfg.Int("port"), test.expectedInt) {
+				t.Fatalf(cmp.Diff(cfg.Int("port"), test.expectedInt))
+			}
+
+			if !cmp.Equal(cfg.Duration("timeout"), test.expectedDuration) {
+				t.Fatalf(cmp.Diff(cfg.Duration("timeout"), test.expectedDuration))
+			}
+
+			if !cmp.Equal(cfg.StringSlice("level"), test.expectedStringSlice) {
+				t.Fatalf(cmp.Diff(cfg.StringSlice("level"), test.expectedStringSlice))
+			}
+
+			if !cmp.Equal(cfg.Sub("database").String("addr"), test.expectedSubString) {
+				t.Fatalf(cmp.Diff(cfg.Sub("database").String("addr"), test.expectedSubString))
+			}
+		})
+	}
+}
+
+func TestNew_Isolated(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenFirst    []config.Option
+		givenSecond   []config.Option
+		givenVar      string
+		expectedFirst string
+	}{
+		{
+			name: "given two configs, expect values of the first to not be replaced by the second",
+			givenFirst: []config.Option{
+				config.WithConfigFile("test.env"),
+				config.WithEnvVars(false),
+			},
+			givenSecond: []config.Option{
+				config.WithConfigFile("bind.yaml"),
+				config.WithEnvVars(false),
+			},
+			givenVar:      "TEST",
+			expectedFirst: "foobar",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first, err := config.New(test.givenFirst...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			second, err := config.New(test.givenSecond...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if !cmp.Equal(first.String(test.givenVar), test.expectedFirst) {
+				t.Fatalf(cmp.Diff(first.String(test.givenVar), test.expectedFirst))
+			}
+
+			if second.IsSet(test.givenVar) {
+				t.Fatalf("expected %s to not be set in second config", test.givenVar)
+			}
+		})
+	}
+}
+
+func TestConfig_Reload(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenInitial  string
+		givenReloaded string
+		expectedError error
+		expectedLevel string
+		expectChange  bool
+	}{
+		{
+			name:          "given a valid change, expect subscribers to be notified and new values used",
+			givenInitial:  "name: books\nlevel: info\n",
+			givenReloaded: "name: books\nlevel: debug\n",
+			expectedLevel: "debug",
+			expectChange:  true,
+		},
+		{
+			name:          "given a change which fails validation, expect it to be rejected and previous values kept",
+			givenInitial:  "name: books\nlevel: info\n",
+			givenReloaded: "name: books\nlevel: verbose\n",
+			expectedError: config.ErrInvalidConfig,
+			expectedLevel: "info",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+
+			writeFile(t, file, test.givenInitial)
+
+			var binding struct {
+				Name  string `mapstructure:"name" validate:"required"`
+				Level string `mapstructure:"level" validate:"oneof=debug info error"`
+			}
+
+			cfg, err := config.New(
+				config.WithConfigFile(file),
+				config.WithEnvVars(false),
+				config.WithBinding(&binding),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			var changed []interface{}
+
+			cfg.OnChange("level", func(old, new interface{}) {
+				changed = append(changed, old, new)
+			})
+
+			writeFile(t, file, test.givenReloaded)
+
+			err = cfg.Reload()
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatalf(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			if !cmp.Equal(cfg.String("level"), test.expectedLevel) {
+				t.Fatalf(cmp.Diff(cfg.String("level"), test.expectedLevel))
+			}
+
+			if test.expectChange && !cmp.Equal(changed, []interface{}{"info", test.expectedLevel}) {
+				t.Fatalf(cmp.Diff(changed, []interface{}{"info", test.expectedLevel}))
+			}
+
+			if !test.expectChange && len(changed) != 0 {
+				t.Fatalf("expected no change, got %v", changed)
+			}
+		})
+	}
+}
+
+func TestConfig_Reload_Concurrent(t *testing.T) {
+	tests := []struct {
+		name         string
+		givenReloads int
+	}{
+		{
+			name:         "given concurrent reloads, expect each change to follow on from the last",
+			givenReloads: 20,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "config.yaml")
+
+			writeFile(t, file, "level: 0\n")
+
+			cfg, err := config.New(config.WithConfigFile(file), config.WithEnvVars(false))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			var changes [][2]interface{}
+
+			cfg.OnChange("level", func(old, new interface{}) {
+				changes = append(changes, [2]interface{}{old, new})
+			})
+
+			var wg sync.WaitGroup
+
+			for i := 1; i <= test.givenReloads; i++ {
+				wg.Add(1)
+
+				go func(i int) {
+					defer wg.Done()
+
+					// Each version is renamed into place, so that no reload reads a partially written file.
+					tmp := filepath.Join(dir, fmt.Sprintf("config-%d.yaml", i))
+
+					err := os.WriteFile(tmp, []byte(fmt.Sprintf("level: %d\n", i)), 0o600)
+					if err == nil {
+						err = os.Rename(tmp, file)
+					}
+
+					if err != nil {
+						t.Errorf("expected nil, got %v", err)
+
+						return
+					}
+
+					err = cfg.Reload()
+					if err != nil {
+						t.Errorf("expected nil, got %v", err)
+					}
+				}(i)
+			}
+
+			wg.Wait()
+
+			for i := 1; i < len(changes); i++ {
+				if !cmp.Equal(changes[i][0], changes[i-1][1]) {
+					t.Fatalf("expected change %d to follow on from %v, got %v", i, changes[i-1][1], changes[i][0])
+				}
+			}
+
+			if len(changes) == 0 {
+				t.Fatal("expected subscribers to be notified")
+			}
+
+			last := changes[len(changes)-1][1]
+			if !cmp.Equal(cfg.Int("level"), last) {
+				t.Fatal(cmp.Diff(cfg.Int("level"), last))
+			}
+		})
+	}
+}
+
+func TestConfig_WithWatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenInitial  string
+		givenReloaded string
+		expectedValue interface{}
+	}{
+		{
+			name:          "given the watched file changes, expect subscribers to be notified",
+			givenInitial:  "level: info\n",
+			givenReloaded: "level: debug\n",
+			expectedValue: "debug",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+
+			writeFile(t, file, test.givenInitial)
+
+			cfg, err := config.New(
+				config.WithConfigFile(file),
+				config.WithEnvVars(false),
+				config.WithWatch(),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer cfg.Close()
+
+			changed := make(chan interface{}, 10)
+
+			cfg.OnChange("level", func(old, new interface{}) {
+				select {
+				case changed <- new:
+				default:
+				}
+			})
+
+			writeFile(t, file, test.givenReloaded)
+
+			// A write may be seen as several events, the first of which can read a partially written file.
+			timeout := time.After(time.Second * 5)
+
+			for {
+				select {
+				case actual := <-changed:
+					if cmp.Equal(actual, test.expectedValue) {
+						return
+					}
+				case <-timeout:
+					t.Fatalf("expected change to %v to be notified", test.expectedValue)
+				}
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, file, contents string) {
+	err := os.WriteFile(file, []byte(contents), 0o600)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+}
+
+func TestNew_Layered(t *testing.T) {
+	tests := []struct {
+		name            string
+		givenFiles      map[string]string
+		givenEnvVars    map[string]string
+		givenFlags      []string
+		expectedValues  map[string]string
+		expectedSources map[string]string
+	}{
+		{
+			name: "given every source, expect each to take precedence over those below it",
+			givenFiles: map[string]string{
+				"config.yaml":            "base: base\ndir: base\noverlay: base\nmysql:\n  addr: base\nflag: base\n",
+				"config.production.yaml": "overlay: overlay\nflag: overlay\n",
+				"conf.d/01.yaml":         "dir: first\n",
+				"conf.d/02.yaml":         "dir: second\noverlay: dir\n",
+			},
+			givenEnvVars: map[string]string{
+				"APP_MYSQL_ADDR": "env",
+			},
+			givenFlags: []string{"--flag=flag"},
+			expectedValues: map[string]string{
+				"base":       "base",
+				"dir":        "second",
+				"overlay":    "overlay",
+				"mysql.addr": "env",
+				"flag":       "flag",
+				"unset":      "default",
+			},
+			expectedSources: map[string]string{
+				"base":       "file:config.yaml",
+				"dir":        "file:conf.d/02.yaml",
+				"overlay":    "file:config.production.yaml",
+				"mysql.addr": "env:APP_MYSQL_ADDR",
+				"flag":       "flag:flag",
+				"unset":      "default",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for name, contents := range test.givenFiles {
+				err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o700)
+				if err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+
+				writeFile(t, filepath.Join(dir, name), contents)
+			}
+
+			for k, v := range test.givenEnvVars {
+				t.Setenv(k, v)
+			}
+
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("flag", "", "")
+
+			err := flags.Parse(test.givenFlags)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			var binding struct {
+				Unset string `mapstructure:"unset" default:"default"`
+			}
+
+			cfg, err := config.New(
+				config.WithConfigFile(filepath.Join(dir, "config.yaml")),
+				config.WithConfigDir(filepath.Join(dir, "conf.d")),
+				config.WithEnvironment("production"),
+				config.WithEnvPrefix("APP"),
+				config.WithFlags(flags),
+				config.WithBinding(&binding),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			for key, expected := range test.expectedValues {
+				if !cmp.Equal(cfg.String(key), expected) {
+					t.Fatalf("%s: %s", key, cmp.Diff(cfg.String(key), expected))
+				}
+			}
+
+			for key, expected := range test.expectedSources {
+				source, ok := cfg.Source(key)
+				if !ok {
+					t.Fatalf("expected source for %s", key)
+				}
+
+				actual := strings.Replace(source.String(), dir+string(filepath.Separator), "", 1)
+
+				if !cmp.Equal(actual, expected) {
+					t.Fatalf("%s: %s", key, cmp.Diff(actual, expected))
+				}
+			}
+		})
+	}
+}
+
+func TestNew_Secrets(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenConfig      string
+		givenSecrets     map[string]string
+		givenEnvVars     map[string]string
+		expectedValues   map[string]string
+		expectedSecrets  []string
+		expectedRedacted map[string]interface{}
+	}{
+		{
+			name:        "given a _FILE env var, expect the key to be read from the file",
+			givenConfig: "mysql:\n  addr: localhost:3306\n",
+			givenSecrets: map[string]string{
+				"mysql-password": "hunter\n",
+			},
+			givenEnvVars: map[string]string{
+				"APP_MYSQL_PASSWORD_FILE": "mysql-password",
+			},
+			expectedValues: map[string]string{
+				"mysql.addr":     "localhost:3306",
+				"mysql.password": "hunter",
+			},
+			expectedSecrets: []string{"mysql.password"},
+			expectedRedacted: map[string]interface{}{
+				"mysql.addr":     "localhost:3306",
+				"mysql.password": config.RedactedValue,
+			},
+		},
+		{
+			name:        "given a file reference, expect the key to be read from the file",
+			givenConfig: "mysql:\n  addr: localhost:3306\n  password: file://{dir}/mysql-password\n",
+			givenSecrets: map[string]string{
+				"mysql-password": "  hunter  ",
+			},
+			expectedValues: map[string]string{
+				"mysql.password": "hunter",
+			},
+			expectedSecrets: []string{"mysql.password"},
+		},
+		{
+			name:        "given a _FILE env var for a key only declared by a binding, expect the key to be read from the file",
+			givenConfig: "mysql:\n  addr: localhost:3306\n",
+			givenSecrets: map[string]string{
+				"token": "secret-token",
+			},
+			givenEnvVars: map[string]string{
+				"APP_TOKEN_FILE": "token",
+			},
+			expectedValues: map[string]string{
+				"token": "secret-token",
+			},
+			expectedSecrets: []string{"token"},
+		},
+		{
+			name:        "given both an env var and a _FILE env var, expect the env var to take precedence",
+			givenConfig: "mysql:\n  addr: localhost:3306\n",
+			givenSecrets: map[string]string{
+				"mysql-password": "hunter",
+			},
+			givenEnvVars: map[string]string{
+				"APP_MYSQL_PASSWORD":      "plain",
+				"APP_MYSQL_PASSWORD_FILE": "mysql-password",
+			},
+			expectedValues: map[string]string{
+				"mysql.password": "plain",
+			},
+		},
+		{
+			name:        "given a _FILE env var for a key which is not secret, expect it to be left alone",
+			givenConfig: "log:\n  level: info\ntls: true\n",
+			givenEnvVars: map[string]string{
+				"APP_LOG_FILE": "app.log",
+				"APP_TLS_FILE": "tls.pem",
+			},
+			expectedValues: map[string]string{
+				"log.level": "info",
+				"tls":       "true",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for name, contents := range test.givenSecrets {
+				writeFile(t, filepath.Join(dir, name), contents)
+			}
+
+			for k, v := range test.givenEnvVars {
+				if strings.HasSuffix(k, "_FILE") {
+					v = filepath.Join(dir, v)
+				}
+
+				t.Setenv(k, v)
+			}
+
+			file := filepath.Join(dir, "config.yaml")
+			writeFile(t, file, strings.ReplaceAll(test.givenConfig, "{dir}", dir))
+
+			var binding struct {
+				Token string `mapstructure:"token"`
+			}
+
+			cfg, err := config.New(
+				config.WithConfigFile(file),
+				config.WithEnvPrefix("APP"),
+				config.WithBinding(&binding),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			for key, expected := range test.expectedValues {
+				if !cmp.Equal(cfg.String(key), expected) {
+					t.Fatalf("%s: %s", key, cmp.Diff(cfg.String(key), expected))
+				}
+			}
+
+			for _, key := range test.expectedSecrets {
+				if !cfg.IsSecret(key) {
+					t.Fatalf("expected %s to be secret", key)
+				}
+			}
+
+			if cfg.IsSecret("mysql.addr") {
+				t.Fatal("expected mysql.addr not to be secret")
+			}
+
+			if test.expectedRedacted != nil && !cmp.Equal(cfg.Redacted(), test.expectedRedacted) {
+				t.Fatal(cmp.Diff(cfg.Redacted(), test.expectedRedacted))
+			}
+		})
+	}
+}
+
+func TestNew_Secrets_Fail(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenConfig   string
+		expectedError error
+	}{
+		{
+			name:          "given a reference to a missing file, expect error",
+			givenConfig:   "mysql:\n  password: file:///does/not/exist\n",
+			expectedError: config.ErrFailedToReadSecret,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			writeFile(t, file, test.givenConfig)
+
+			_, err := config.New(config.WithConfigFile(file), config.WithEnvVars(false))
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestConfig_WithWatch_SecretRotation(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenInitial  string
+		givenRotated  string
+		expectedValue interface{}
+	}{
+		{
+			name:          "given a secret file is rotated, expect subscribers to be notified",
+			givenInitial:  "hunter\n",
+			givenRotated:  "hunter2\n",
+			expectedValue: "hunter2",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configDir, secretDir := t.TempDir(), t.TempDir()
+
+			secret := filepath.Join(secretDir, "password")
+			writeFile(t, secret, test.givenInitial)
+
+			file := filepath.Join(configDir, "config.yaml")
+			writeFile(t, file, "mysql:\n  password: file://"+secret+"\n")
+
+			cfg, err := config.New(
+				config.WithConfigFile(file),
+				config.WithEnvVars(false),
+				config.WithWatch(),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer cfg.Close()
+
+			changed := make(chan interface{}, 10)
+
+			cfg.OnChange("mysql.password", func(old, new interface{}) {
+				select {
+				case changed <- new:
+				default:
+				}
+			})
+
+			writeFile(t, secret, test.givenRotated)
+
+			timeout := time.After(time.Second * 5)
+
+			for {
+				select {
+				case actual := <-changed:
+					if cmp.Equal(actual, test.expectedValue) {
+						return
+					}
+				case <-timeout:
+					t.Fatalf("expected change to %v to be notified", test.expectedValue)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	dependenciesKey = "dependencies"
+)
+
+var (
+	ErrInvalidDependencies = errors.New("failed to read dependencies from config")
+)
+
+// Dependencies describes every dependency which can be declared under the dependencies key of a config file.
+// Each Redis, MySQL, Postgres, Publisher and Subscriber is keyed by the name it is registered under. Optional values are
+// pointers so that the dependency's own default is used whenever they are absent.
+type Dependencies struct {
+	Redis       map[string]Redis      `mapstructure:"redis"`
+	MySQL       map[string]MySQL      `mapstructure:"mysql"`
+	Postgres    map[string]Postgres   `mapstructure:"postgres"`
+	Publishers  map[string]Publisher  `mapstructure:"publishers"`
+	Subscribers map[string]Subscriber `mapstructure:"subscribers"`
+	Router      *Router               `mapstructure:"router"`
+	Server      *Server               `mapstructure:"server"`
+}
+
+type Redis struct {
+	Addrs            []string       `mapstructure:"addrs"`
+	MasterName       *string        `mapstructure:"masterName"`
+	Username         *string        `mapstructure:"username"`
+	Password         *string        `mapstructure:"password"`
+	SentinelPassword *string        `mapstructure:"sentinelPassword"`
+	DB               *int           `mapstructure:"db"`
+	TLS              *RedisTLS      `mapstructure:"tls"`
+	PoolSize         *int           `mapstructure:"poolSize"`
+	MinIdleConns     *int           `mapstructure:"minIdleConns"`
+	DialTimeout      *time.Duration `mapstructure:"dialTimeout"`
+	ReadTimeout      *time.Duration `mapstructure:"readTimeout"`
+	WriteTimeout     *time.Duration `mapstructure:"writeTimeout"`
+	MaxRetries       *int           `mapstructure:"maxRetries"`
+	// ClientType is one of failover, nonfailover or cluster.
+	ClientType *string `mapstructure:"clientType"`
+}
+
+type RedisTLS struct {
+	CAFile     string `mapstructure:"caFile"`
+	CertFile   string `mapstructure:"certFile"`
+	KeyFile    string `mapstructure:"keyFile"`
+	ServerName string `mapstructure:"serverName"`
+}
+
+// MySQL is connected to via the DSN given by Addr, the structured fields, or both, in which case the structured
+// fields take precedence.
+type MySQL struct {
+	Addr               string            `mapstructure:"addr"`
+	Host               *string           `mapstructure:"host"`
+	Port               *int              `mapstructure:"port"`
+	User               *string           `mapstructure:"user"`
+	Password           *string           `mapstructure:"password"`
+	Database           *string           `mapstructure:"database"`
+	Params             map[string]string `mapstructure:"params"`
+	DialTimeout        *time.Duration    `mapstructure:"dialTimeout"`
+	ReadTimeout        *time.Duration    `mapstructure:"readTimeout"`
+	WriteTimeout       *time.Duration    `mapstructure:"writeTimeout"`
+	TLS                *MySQLTLS         `mapstructure:"tls"`
+	Replicas           []string          `mapstructure:"replicas"`
+	ReplicaHosts       []string          `mapstructure:"replicaHosts"`
+	MaxLifetime        *time.Duration    `mapstructure:"maxLifetime"`
+	MaxOpenConnections *int              `mapstructure:"maxOpenConnections"`
+	MaxIdleConnections *int              `mapstructure:"maxIdleConnections"`
+	SlowQueryThreshold *time.Duration    `mapstructure:"slowQueryThreshold"`
+}
+
+type MySQLTLS struct {
+	CAFile     string `mapstructure:"caFile"`
+	CertFile   string `mapstructure:"certFile"`
+	KeyFile    string `mapstructure:"keyFile"`
+	ServerName string `mapstructure:"serverName"`
+}
+
+type Postgres struct {
+	Addr               string         `mapstructure:"addr"`
+	MaxLifetime        *time.Duration `mapstructure:"maxLifetime"`
+	MaxOpenConnections *int           `mapstructure:"maxOpenConnections"`
+	MaxIdleConnections *int           `mapstructure:"maxIdleConnections"`
+}
+
+type Publisher struct {
+	Addrs        []string       `mapstructure:"addrs"`
+	Topic        string         `mapstructure:"topic"`
+	MaxAttempts  *int           `mapstructure:"maxAttempts"`
+	WriteTimeout *time.Duration `mapstructure:"writeTimeout"`
+	// RequiredAck is one of none, one or all.
+	RequiredAck *string `mapstructure:"requiredAck"`
+}
+
+type Subscriber struct {
+	Addrs       []string `mapstructure:"addrs"`
+	Topic       string   `mapstructure:"topic"`
+	MaxAttempts *int     `mapstructure:"maxAttempts"`
+}
+
+type Router struct {
+	TracerKey *string `mapstructure:"tracerKey"`
+}
+
+type Server struct {
+	Addr         *string        `mapstructure:"addr"`
+	AdminAddr    *string        `mapstructure:"adminAddr"`
+	ReadTimeout  *time.Duration `mapstructure:"readTimeout"`
+	WriteTimeout *time.Duration `mapstructure:"writeTimeout"`
+	TLS          *TLS           `mapstructure:"tls"`
+}
+
+type TLS struct {
+	CertFile string `mapstructure:"certFile"`
+	KeyFile  string `mapstructure:"keyFile"`
+}
+
+// Dependencies reads every dependency declared under the dependencies key.
+func (c *Config) Dependencies() (*Dependencies, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	d := &Dependencies{}
+
+	err := c.viper.UnmarshalKey(dependenciesKey, d)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidDependencies)
+	}
+
+	return d, nil
+}
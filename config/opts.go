@@ -1,13 +1,89 @@
 package config
 
+import (
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
 func WithConfigFile(file string) Option {
 	return func(config *Config) {
 		config.file = file
 	}
 }
 
+// WithConfigDir reads every supported file within the given directory, in alphabetical order, over the config file.
+func WithConfigDir(dir string) Option {
+	return func(config *Config) {
+		config.dir = dir
+	}
+}
+
+// WithEnvironment reads the environment-specific overlay of the config file, should it exist, over every other file.
+// Given production, config.production.yaml is read over config.yaml.
+func WithEnvironment(environment string) Option {
+	return func(config *Config) {
+		config.environment = environment
+	}
+}
+
+// WithEnvPrefix only reads environment variables beginning with the given prefix, so that given APP, APP_MYSQL_ADDR
+// is read for mysql.addr.
+func WithEnvPrefix(prefix string) Option {
+	return func(config *Config) {
+		config.envPrefix = prefix
+	}
+}
+
+// WithEnvKeyReplacer sets how keys are turned into the names of environment variables. By default, dots are replaced
+// with underscores.
+func WithEnvKeyReplacer(replacer *strings.Replacer) Option {
+	return func(config *Config) {
+		config.envReplacer = replacer
+	}
+}
+
+// WithFlags reads every flag of the given set which has been set, each flag being named by the key it supplies.
+func WithFlags(flags *pflag.FlagSet) Option {
+	return func(config *Config) {
+		config.flags = flags
+	}
+}
+
 func WithEnvVars(enable bool) Option {
 	return func(config *Config) {
 		config.enableEnvVars = enable
 	}
 }
+
+// WithBinding binds the config into the struct pointed to by v once it has been loaded, failing New should it not
+// pass validation. See Config.Bind for the tags supported.
+func WithBinding(v interface{}) Option {
+	return func(config *Config) {
+		config.bindings = append(config.bindings, v)
+	}
+}
+
+// WithRedactedPatterns redacts the value of every key matching any of the given patterns within Redacted, alongside
+// those keys which commonly hold credentials, such as passwords and tokens.
+func WithRedactedPatterns(patterns ...*regexp.Regexp) Option {
+	return func(config *Config) {
+		config.redactedPatterns = append(config.redactedPatterns, patterns...)
+	}
+}
+
+// WithWatch reloads the config whenever its file changes or the process receives SIGHUP. See Config.Reload.
+func WithWatch() Option {
+	return func(config *Config) {
+		config.watch = true
+	}
+}
+
+// WithLogger sets the logger used to report reloads.
+func WithLogger(logger *zap.Logger) Option {
+	return func(config *Config) {
+		config.logger = logger
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces the value of every secret key within Redacted.
+const RedactedValue = "[REDACTED]"
+
+var (
+	// defaultRedactedPattern matches keys which commonly hold credentials, such as mysql.password or api.token.
+	defaultRedactedPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|credentials?|dsn)$`)
+
+	// userPattern matches the user of a DSN or URL up to the colon which begins its password, such as root: within
+	// root:hunter@(localhost:3306)/db.
+	userPattern = regexp.MustCompile(`^[^:/@\s]*(?:://[^:/@\s]*)?:`)
+)
+
+// Redacted returns the value of every set key for logs and config dumps. The value of each secret key, and of each key
+// matching a redacted pattern, is replaced by RedactedValue, whilst any password held within a DSN or URL is replaced
+// in place, so that root:hunter@(localhost:3306)/db becomes root:[REDACTED]@(localhost:3306)/db.
+func (c *Config) Redacted() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	settings := make(map[string]interface{})
+
+	for _, key := range c.viper.AllKeys() {
+		if !c.viper.IsSet(key) {
+			continue
+		}
+
+		if c.isRedacted(key) {
+			settings[key] = RedactedValue
+
+			continue
+		}
+
+		settings[key] = redactValue(c.viper.Get(key))
+	}
+
+	return settings
+}
+
+func (c *Config) isRedacted(key string) bool {
+	return c.sources[key].Kind == SourceSecret || c.matchesRedactedPattern(key)
+}
+
+// matchesRedactedPattern reports whether the given key is marked secret by the default or given redacted patterns.
+func (c *Config) matchesRedactedPattern(key string) bool {
+	for _, pattern := range c.redactedPatterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactValue replaces the password of every DSN or URL held by v.
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return redactCredentials(value)
+	case []string:
+		redacted := make([]string, 0, len(value))
+
+		for _, s := range value {
+			redacted = append(redacted, redactCredentials(s))
+		}
+
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, 0, len(value))
+
+		for _, e := range value {
+			redacted = append(redacted, redactValue(e))
+		}
+
+		return redacted
+	default:
+		return v
+	}
+}
+
+// redactCredentials replaces the password of a DSN or URL. As a password may itself hold an @, such as p@ss within
+// root:p@ss@tcp(localhost:3306)/db, it runs up to the last @ before the path, which for a DSN follows its last slash
+// and for a URL its first.
+func redactCredentials(s string) string {
+	user := userPattern.FindString(s)
+	if user == "" {
+		return s
+	}
+
+	rest := s[len(user):]
+
+	end := strings.LastIndex(rest, "/")
+	if strings.Contains(user, "://") {
+		end = strings.IndexAny(rest, "/?#")
+	}
+
+	if end == -1 {
+		end = len(rest)
+	}
+
+	at := strings.LastIndex(rest[:end], "@")
+	if at == -1 || strings.ContainsAny(rest[:at], " \t\r\n") {
+		return s
+	}
+
+	return user + RedactedValue + rest[at:]
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	secretFilePrefix = "file://"
+	secretEnvSuffix  = "_FILE"
+)
+
+var ErrFailedToReadSecret = errors.New("failed to read secret file")
+
+// IsSecret reports whether the value of the given key was read from a secret file.
+func (c *Config) IsSecret(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.sources[strings.ToLower(key)].Kind == SourceSecret
+}
+
+// resolveSecrets reads the value of a key from a file whenever its KEY_FILE environment variable is set, or its value
+// is a file:// reference, returning the file each secret key was read from. The given keys are checked alongside
+// every key v already knows of, so that keys which are only declared by bindings may be read from files too.
+//
+// Only keys matching a redacted pattern are read from a KEY_FILE environment variable, as variables such as LOG_FILE
+// or TLS_FILE commonly name files which are not secrets. Such a variable is read as though it were part of the config
+// files, so that KEY itself and flags still take precedence over it.
+func (c *Config) resolveSecrets(v *viper.Viper, keys []string) (map[string]Source, error) {
+	secrets := make(map[string]Source)
+
+	keys = mergeKeys(v.AllKeys(), keys)
+
+	if c.enableEnvVars && c.envPrefix != "" {
+		keys = mergeKeys(keys, c.secretEnvKeys(keys))
+	}
+
+	for _, key := range keys {
+		overridden := c.overriddenBy(key) != nil
+
+		path, ok := "", false
+
+		if c.enableEnvVars && !overridden && c.matchesRedactedPattern(key) {
+			path, ok = os.LookupEnv(c.envName(key) + secretEnvSuffix)
+		}
+
+		if !ok {
+			ref, isString := v.Get(key).(string)
+			if !isString || !strings.HasPrefix(ref, secretFilePrefix) {
+				continue
+			}
+
+			path = strings.TrimPrefix(ref, secretFilePrefix)
+		}
+
+		value, err := readSecret(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v: %w", key, err, ErrFailedToReadSecret)
+		}
+
+		if overridden {
+			v.Set(key, value)
+		} else {
+			err = v.MergeConfigMap(nestKey(key, value))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v: %w", key, err, ErrFailedToReadSecret)
+			}
+		}
+
+		secrets[key] = Source{Kind: SourceSecret, Name: path}
+	}
+
+	return secrets, nil
+}
+
+// secretEnvKeys returns the key of every prefixed KEY_FILE environment variable which does not belong to any of the
+// given known keys. As environment variables cannot tell dots apart from underscores, each is read as a nested key, so
+// that APP_MYSQL_PASSWORD_FILE is read for mysql.password. Unprefixed variables are never scanned, as many, such as
+// SSL_CERT_FILE, name files which are not secrets.
+func (c *Config) secretEnvKeys(known []string) []string {
+	names := make(map[string]bool, len(known))
+
+	for _, key := range known {
+		names[c.envName(key)+secretEnvSuffix] = true
+	}
+
+	prefix := strings.ToUpper(c.envPrefix) + "_"
+
+	var keys []string
+
+	for _, env := range os.Environ() {
+		name := strings.SplitN(env, "=", 2)[0]
+		if names[name] || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, secretEnvSuffix) {
+			continue
+		}
+
+		key := strings.TrimSuffix(strings.TrimPrefix(name, prefix), secretEnvSuffix)
+		if key == "" {
+			continue
+		}
+
+		keys = append(keys, strings.ToLower(strings.ReplaceAll(key, "_", ".")))
+	}
+
+	return keys
+}
+
+// overriddenBy returns the flag or environment variable which supplies the given key over every config file, if any.
+func (c *Config) overriddenBy(key string) *Source {
+	if c.flags != nil {
+		f := c.flags.Lookup(key)
+		if f != nil && f.Changed {
+			return &Source{Kind: SourceFlag, Name: f.Name}
+		}
+	}
+
+	if c.enableEnvVars {
+		name := c.envName(key)
+
+		_, ok := os.LookupEnv(name)
+		if ok {
+			return &Source{Kind: SourceEnv, Name: name}
+		}
+	}
+
+	return nil
+}
+
+// secretFiles returns every secret file which is currently read.
+func (c *Config) secretFiles() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var files []string
+
+	for _, s := range c.sources {
+		if s.Kind == SourceSecret {
+			files = append(files, s.Name)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+func readSecret(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// bindingKeys returns the key of every field of the given bindings. Invalid bindings are skipped, as bind reports them.
+func bindingKeys(bindings []interface{}) []string {
+	var keys []string
+
+	for _, b := range bindings {
+		rv := reflect.ValueOf(b)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+			continue
+		}
+
+		for _, f := range collectFields("", rv.Elem()) {
+			keys = append(keys, f.key)
+		}
+	}
+
+	return keys
+}
+
+func mergeKeys(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+
+	var keys []string
+
+	for _, key := range append(append([]string(nil), a...), b...) {
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// nestKey turns a key such as mysql.password into the nested map viper merges config files as.
+func nestKey(key string, value interface{}) map[string]interface{} {
+	parts := strings.Split(key, ".")
+
+	m := map[string]interface{}{parts[len(parts)-1]: value}
+
+	for i := len(parts) - 2; i >= 0; i-- {
+		m = map[string]interface{}{parts[i]: m}
+	}
+
+	return m
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceSecret  = "secret"
+)
+
+// Source describes where the value of a key was read from. Name holds the file's path, the environment variable's
+// name, the flag's name or the secret file's path, and is empty for defaults.
+type Source struct {
+	Kind string
+	Name string
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+
+	return fmt.Sprintf("%s:%s", s.Kind, s.Name)
+}
+
+// Source returns where the value of the given key was read from. Should the key not be set, false is returned.
+func (c *Config) Source(key string) (Source, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s, ok := c.sources[strings.ToLower(key)]
+
+	return s, ok
+}
+
+// Sources returns where the value of every set key was read from.
+func (c *Config) Sources() map[string]Source {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sources := make(map[string]Source, len(c.sources))
+
+	for k, s := range c.sources {
+		sources[k] = s
+	}
+
+	return sources
+}
+
+// layerFiles returns every config file to be read, from lowest to highest precedence.
+func (c *Config) layerFiles() ([]string, error) {
+	var files []string
+
+	if c.file != "" {
+		files = append(files, c.file)
+	}
+
+	if c.dir != "" {
+		entries, err := ioutil.ReadDir(c.dir)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", err, ErrFailedToReadConfigDir)
+		}
+
+		var dirFiles []string
+
+		for _, entry := range entries {
+			if entry.IsDir() || !isSupportedFile(entry.Name()) {
+				continue
+			}
+
+			dirFiles = append(dirFiles, filepath.Join(c.dir, entry.Name()))
+		}
+
+		sort.Strings(dirFiles)
+
+		files = append(files, dirFiles...)
+	}
+
+	overlay := c.overlayFile()
+	if overlay != "" {
+		_, err := os.Stat(overlay)
+		if err == nil {
+			files = append(files, overlay)
+		}
+	}
+
+	return files, nil
+}
+
+// overlayFile returns the environment-specific overlay of the base file, i.e. config.production.yaml for config.yaml.
+func (c *Config) overlayFile() string {
+	if c.file == "" || c.environment == "" {
+		return ""
+	}
+
+	ext := filepath.Ext(c.file)
+
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(c.file, ext), c.environment, ext)
+}
+
+func isSupportedFile(name string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+
+	for _, supported := range viper.SupportedExts {
+		if ext == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeFiles merges each file into v in order, returning which file last supplied each key.
+func mergeFiles(v *viper.Viper, files []string) (map[string]Source, error) {
+	sources := make(map[string]Source)
+
+	for i, file := range files {
+		layer := viper.New()
+		layer.SetConfigFile(file)
+
+		err := layer.ReadInConfig()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", err, ErrFailedToLoadConfigFile)
+		}
+
+		if i == 0 {
+			v.SetConfigFile(file)
+		}
+
+		err = v.MergeConfigMap(layer.AllSettings())
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", err, ErrFailedToLoadConfigFile)
+		}
+
+		for _, key := range layer.AllKeys() {
+			sources[key] = Source{Kind: SourceFile, Name: file}
+		}
+	}
+
+	return sources, nil
+}
+
+// resolveSources works out which source supplied every key of v, following the precedence documented on Config.
+func (c *Config) resolveSources(v *viper.Viper, fileSources, secretSources map[string]Source) map[string]Source {
+	sources := make(map[string]Source)
+
+	for _, key := range v.AllKeys() {
+		s, ok := secretSources[key]
+		if ok {
+			sources[key] = s
+
+			continue
+		}
+
+		override := c.overriddenBy(key)
+		if override != nil {
+			sources[key] = *override
+
+			continue
+		}
+
+		s, ok = fileSources[key]
+		if ok {
+			sources[key] = s
+
+			continue
+		}
+
+		sources[key] = Source{Kind: SourceDefault}
+	}
+
+	return sources
+}
+
+// envName returns the environment variable read for the given key, matching viper's own naming.
+func (c *Config) envName(key string) string {
+	name := strings.ToUpper(key)
+	if c.envPrefix != "" {
+		name = strings.ToUpper(c.envPrefix) + "_" + name
+	}
+
+	if c.envReplacer != nil {
+		name = c.envReplacer.Replace(name)
+	}
+
+	return name
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// ChangeFunc is notified of the previous and current value of a key once a reload changes it.
+type ChangeFunc func(old, new interface{})
+
+// OnChange registers fn to be notified whenever a reload changes the value of the given key. Nested keys, such as
+// server.addr, as well as whole sections, such as server, may be subscribed to.
+func (c *Config) OnChange(key string, fn ChangeFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribers[key] = append(c.subscribers[key], fn)
+}
+
+// Reload re-reads every source. Should the result fail to load or to validate against any binding, it is rejected
+// and the previous values are kept. Otherwise, every subscriber to a key whose value has changed is notified.
+//
+// Reloads are serialised, each reading, swapping in and notifying subscribers of its values before the next begins,
+// so that a slow reload cannot replace the values of a later one. As such, subscribers must not call Reload.
+func (c *Config) Reload() error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	c.mu.RLock()
+	bindings := make([]interface{}, 0, len(c.bindings))
+
+	for _, b := range c.bindings {
+		bindings = append(bindings, newBindingOf(b))
+	}
+	c.mu.RUnlock()
+
+	v, sources, err := c.load(bindings)
+	if err != nil {
+		c.logger.Error("rejected config reload", zap.Error(err))
+
+		return err
+	}
+
+	c.mu.Lock()
+	old := c.viper
+	c.viper = v
+	c.sources = sources
+
+	type change struct {
+		fns      []ChangeFunc
+		old, new interface{}
+	}
+
+	var changes []change
+
+	for key, fns := range c.subscribers {
+		o, n := old.Get(key), v.Get(key)
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+
+		changes = append(changes, change{
+			fns: append([]ChangeFunc(nil), fns...),
+			old: o,
+			new: n,
+		})
+	}
+	c.mu.Unlock()
+
+	c.logger.Info("reloaded config")
+
+	for _, ch := range changes {
+		for _, fn := range ch.fns {
+			fn(ch.old, ch.new)
+		}
+	}
+
+	return nil
+}
+
+// Close stops the config from being watched.
+func (c *Config) Close() error {
+	c.mu.Lock()
+	stop := c.stopWatching
+	c.stopWatching = nil
+	c.mu.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+
+	return nil
+}
+
+// startWatching reloads the config whenever any of its files change or SIGHUP is received.
+func (c *Config) startWatching() error {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	var events chan fsnotify.Event
+
+	watcher, err := c.watchFiles()
+	if err != nil {
+		signal.Stop(hangups)
+
+		return err
+	}
+
+	if watcher != nil {
+		events = watcher.Events
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		realFiles := c.resolveLinks()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-hangups:
+				c.logger.Info("received SIGHUP, reloading config")
+				c.Reload()
+			case event, ok := <-events:
+				if !ok {
+					events = nil
+
+					continue
+				}
+
+				currentFiles := c.resolveLinks()
+
+				changed := c.isWatchedFile(event.Name) &&
+					event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0
+				swapped := !reflect.DeepEqual(currentFiles, realFiles)
+
+				if !changed && !swapped {
+					continue
+				}
+
+				realFiles = currentFiles
+
+				c.Reload()
+
+				// Secret files may only have been referenced by the reload.
+				for dir := range c.watchDirs() {
+					watcher.Add(dir)
+				}
+			}
+		}
+	}()
+
+	c.stopWatching = func() {
+		close(done)
+		signal.Stop(hangups)
+
+		if watcher != nil {
+			watcher.Close()
+		}
+	}
+
+	return nil
+}
+
+// watchFiles watches the directory of every config and secret file, rather than the files themselves, so that files
+// replaced via a rename or a symlink swap, as with Kubernetes ConfigMap and secret mounts, are still seen.
+func (c *Config) watchFiles() (*fsnotify.Watcher, error) {
+	dirs := c.watchDirs()
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrFailedToWatch)
+	}
+
+	for dir := range dirs {
+		err = watcher.Add(dir)
+		if err != nil {
+			watcher.Close()
+
+			return nil, fmt.Errorf("%v: %w", err, ErrFailedToWatch)
+		}
+	}
+
+	return watcher, nil
+}
+
+func (c *Config) watchDirs() map[string]bool {
+	dirs := make(map[string]bool)
+
+	if c.file != "" {
+		dirs[filepath.Dir(c.file)] = true
+	}
+
+	if c.dir != "" {
+		dirs[filepath.Clean(c.dir)] = true
+	}
+
+	for _, file := range c.secretFiles() {
+		dirs[filepath.Dir(file)] = true
+	}
+
+	return dirs
+}
+
+func (c *Config) isWatchedFile(name string) bool {
+	name = filepath.Clean(name)
+
+	if c.file != "" && (name == filepath.Clean(c.file) || name == filepath.Clean(c.overlayFile())) {
+		return true
+	}
+
+	for _, file := range c.secretFiles() {
+		if name == filepath.Clean(file) {
+			return true
+		}
+	}
+
+	return c.dir != "" && filepath.Dir(name) == filepath.Clean(c.dir) && isSupportedFile(name)
+}
+
+// resolveLinks returns the target of every config and secret file which is currently read.
+func (c *Config) resolveLinks() map[string]string {
+	files, _ := c.layerFiles()
+
+	links := make(map[string]string, len(files))
+
+	for _, file := range append(files, c.secretFiles()...) {
+		links[file], _ = filepath.EvalSymlinks(file)
+	}
+
+	return links
+}
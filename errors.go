@@ -0,0 +1,90 @@
+package cgs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OptionError records which dependency an Option failed to construct.
+type OptionError struct {
+	Kind string
+	Name string
+	Err  error
+}
+
+func newOptionError(kind, name string, err error) error {
+	return &OptionError{
+		Kind: kind,
+		Name: name,
+		Err:  err,
+	}
+}
+
+func (o *OptionError) Error() string {
+	if o.Name == "" {
+		return fmt.Sprintf("%s: %s", o.Kind, o.Err)
+	}
+
+	return fmt.Sprintf("%s-%s: %s", o.Name, o.Kind, o.Err)
+}
+
+func (o *OptionError) Unwrap() error {
+	return o.Err
+}
+
+// MultiError aggregates the errors raised by several dependencies. It matches its sentinel as well as
+// every wrapped error when used with errors.Is and errors.As.
+type MultiError struct {
+	sentinel error
+	errs     []error
+}
+
+func newMultiError(sentinel error, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &MultiError{
+		sentinel: sentinel,
+		errs:     errs,
+	}
+}
+
+func (m *MultiError) Error() string {
+	msgs := make([]string, 0, len(m.errs))
+
+	for _, err := range m.errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%s: %s", strings.Join(msgs, "; "), m.sentinel)
+}
+
+func (m *MultiError) Errors() []error {
+	return m.errs
+}
+
+func (m *MultiError) Unwrap() error {
+	return m.sentinel
+}
+
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package cgs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// group runs functions concurrently and cancels their shared context as soon as one of them fails.
+// Unlike errgroup it keeps every error rather than only the first.
+type group struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	errs   []error
+}
+
+func newGroup(ctx context.Context) (*group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &group{cancel: cancel}, ctx
+}
+
+func (g *group) Go(ctx context.Context, fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		err := g.call(ctx, fn)
+		if err == nil {
+			return
+		}
+
+		g.mu.Lock()
+		g.errs = append(g.errs, err)
+		g.mu.Unlock()
+
+		g.cancel()
+	}()
+}
+
+func (g *group) call(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func (g *group) Wait() []error {
+	g.wg.Wait()
+
+	return g.errs
+}
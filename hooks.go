@@ -0,0 +1,59 @@
+package cgs
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	stageStart = "start"
+	stageStop  = "stop"
+)
+
+// Hook is service-specific work run at a defined point of the application's lifecycle. Each Hook is given its own
+// context which is cancelled once the application's hook timeout passes.
+type Hook func(ctx context.Context) error
+
+// runHooks runs the given hooks in order. OnStart hooks stop at the first failure whereas every OnStop hook is run.
+func (a *Application) runHooks(ctx context.Context, stage string, hooks []Hook) error {
+	var errs []error
+
+	for i, hook := range hooks {
+		err := a.runHook(ctx, hook)
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("%s hook %d failed", stage, i), zap.Error(err))
+
+			errs = append(errs, fmt.Errorf("%s hook %d: %w", stage, i, err))
+
+			if stage == stageStart {
+				break
+			}
+
+			continue
+		}
+
+		a.logger.Info(fmt.Sprintf("ran %s hook %d", stage, i))
+	}
+
+	return newMultiError(ErrHookFailed, errs)
+}
+
+func (a *Application) runHook(ctx context.Context, hook Hook) error {
+	ctx, cancel := context.WithTimeout(ctx, a.hookTimeout)
+	defer cancel()
+
+	c := make(chan error, 1)
+
+	go func() {
+		c <- hook(ctx)
+	}()
+
+	select {
+	case err := <-c:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,30 @@
+// Package detached provides contexts which outlive the cancellation of their parent.
+package detached
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext keeps the values of its parent but is never cancelled by it.
+type detachedContext struct {
+	context.Context
+}
+
+// WithoutCancel returns a context holding the values of ctx, such as request IDs, which is neither cancelled nor
+// given a deadline along with it.
+func WithoutCancel(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,327 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jamieaitken/requestid"
+	"go.uber.org/zap"
+)
+
+const (
+	operationConnect  = "connect"
+	operationBegin    = "begin"
+	operationCommit   = "commit"
+	operationRollback = "rollback"
+	operationPrepare  = "prepare"
+	operationPing     = "ping"
+	operationOther    = "other"
+)
+
+// statements are the leading keywords used as the operation of a query. Any other query is counted as other, keeping
+// the number of label values bounded.
+var statements = map[string]bool{
+	"select": true, "insert": true, "update": true, "delete": true, "replace": true, "with": true,
+	"create": true, "alter": true, "drop": true, "truncate": true, "rename": true,
+	"show": true, "set": true, "call": true, "lock": true, "unlock": true,
+	"start": true, "begin": true, "commit": true, "rollback": true, "savepoint": true, "release": true,
+}
+
+// Query is a single query made through the dependency, be it plain, prepared or within a transaction.
+type Query struct {
+	// Name is the name of the pool queried, such as books or books-replica-0.
+	Name      string
+	Operation string
+	// SQL is the query as given, its args left as placeholders.
+	SQL string
+	// Args is the number of args given, their values withheld as they may hold personal data or secrets.
+	Args     int
+	Duration time.Duration
+	Err      error
+}
+
+// QueryHook is called once each query completes, such as to trace it. Hooks are called synchronously, so must be
+// quick.
+type QueryHook func(ctx context.Context, query Query)
+
+// driverConn is every interface implemented by the connections of the MySQL driver.
+type driverConn interface {
+	driver.Conn
+	driver.ConnPrepareContext
+	driver.ConnBeginTx
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+	driver.NamedValueChecker
+}
+
+// driverStmt is every interface implemented by the statements of the MySQL driver.
+type driverStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+	driver.NamedValueChecker
+	driver.ColumnConverter
+}
+
+// connector instruments every connection of the wrapped connector, so that each path through database/sql, be it a
+// plain query, a prepared statement or a transaction, is observed.
+type connector struct {
+	driver.Connector
+	observer *observer
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	start := time.Now()
+
+	dc, err := c.Connector.Connect(ctx)
+	c.observer.observe(operationConnect, start, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	inner, ok := dc.(driverConn)
+	if !ok {
+		return dc, nil
+	}
+
+	return &conn{driverConn: inner, observer: c.observer}, nil
+}
+
+type conn struct {
+	driverConn
+	observer *observer
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+
+	s, err := c.driverConn.PrepareContext(ctx, query)
+	c.observer.observe(operationPrepare, start, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	inner, ok := s.(driverStmt)
+	if !ok {
+		return s, nil
+	}
+
+	return &stmt{driverStmt: inner, observer: c.observer, query: query}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+
+	t, err := c.driverConn.BeginTx(ctx, opts)
+	c.observer.observe(operationBegin, start, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx{Tx: t, observer: c.observer}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	res, err := c.driverConn.ExecContext(ctx, query, args)
+	c.observer.observeQuery(ctx, query, len(args), start, err)
+
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+
+	rows, err := c.driverConn.QueryContext(ctx, query, args)
+	c.observer.observeQuery(ctx, query, len(args), start, err)
+
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	start := time.Now()
+
+	err := c.driverConn.Ping(ctx)
+	c.observer.observe(operationPing, start, err)
+
+	return err
+}
+
+type stmt struct {
+	driverStmt
+	observer *observer
+	query    string
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	res, err := s.driverStmt.ExecContext(ctx, args)
+	s.observer.observeQuery(ctx, s.query, len(args), start, err)
+
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+
+	rows, err := s.driverStmt.QueryContext(ctx, args)
+	s.observer.observeQuery(ctx, s.query, len(args), start, err)
+
+	return rows, err
+}
+
+type tx struct {
+	driver.Tx
+	observer *observer
+}
+
+func (t *tx) Commit() error {
+	start := time.Now()
+
+	err := t.Tx.Commit()
+	t.observer.observe(operationCommit, start, err)
+
+	return err
+}
+
+func (t *tx) Rollback() error {
+	start := time.Now()
+
+	err := t.Tx.Rollback()
+	t.observer.observe(operationRollback, start, err)
+
+	return err
+}
+
+// observer records the operations of a single connection pool, labelled by its name, logging queries slower than
+// the threshold given by WithSlowQueryThreshold and passing each query to the hooks given by WithQueryHook.
+type observer struct {
+	name          string
+	slowThreshold time.Duration
+	logger        *zap.Logger
+	requestIDKey  requestid.Key
+	hooks         []QueryHook
+}
+
+// observe records the duration of an operation, and whether it failed. driver.ErrSkip is not recorded, as
+// database/sql retries the operation via another path which is observed in turn.
+func (o *observer) observe(operation string, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	operationCount.WithLabelValues(o.name, operation).Inc()
+	duration.WithLabelValues(o.name, operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		errorCount.WithLabelValues(o.name, operation).Inc()
+	}
+}
+
+// observeQuery observes a query as any other operation, labelled by its leading keyword, before logging it should it
+// be slow and passing it to every hook.
+func (o *observer) observeQuery(ctx context.Context, query string, args int, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	q := Query{
+		Name:      o.name,
+		Operation: operationOf(query),
+		SQL:       query,
+		Args:      args,
+		Duration:  time.Since(start),
+		Err:       err,
+	}
+
+	o.observe(q.Operation, start, err)
+
+	if o.slowThreshold > 0 && q.Duration >= o.slowThreshold {
+		o.logSlow(ctx, q)
+	}
+
+	for _, hook := range o.hooks {
+		hook(ctx, q)
+	}
+}
+
+// logSlow logs the given query with each of its args redacted, as they may hold personal data or secrets.
+func (o *observer) logSlow(ctx context.Context, q Query) {
+	args := make([]string, q.Args)
+	for i := range args {
+		args[i] = redactedValue
+	}
+
+	fields := []zap.Field{
+		zap.String("name", q.Name),
+		zap.String("operation", q.Operation),
+		zap.String("sql", q.SQL),
+		zap.Strings("args", args),
+		zap.Duration("duration", q.Duration),
+	}
+
+	id, ok := ctx.Value(o.requestIDKey).(string)
+	if ok {
+		fields = append(fields, zap.String("request_id", id))
+	}
+
+	if q.Err != nil {
+		fields = append(fields, zap.Error(q.Err))
+	}
+
+	o.logger.Warn("slow mysql query", fields...)
+}
+
+// operationOf returns the leading keyword of the given query, such as select or insert.
+func operationOf(query string) string {
+	query = strings.TrimLeft(query, " \t\r\n(")
+
+	end := strings.IndexAny(query, " \t\r\n(;")
+	if end == -1 {
+		end = len(query)
+	}
+
+	keyword := strings.ToLower(query[:end])
+	if !statements[keyword] {
+		return operationOther
+	}
+
+	return keyword
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, 0, len(args))
+
+	for i, arg := range args {
+		named = append(named, driver.NamedValue{Ordinal: i + 1, Value: arg})
+	}
+
+	return named
+}
@@ -0,0 +1,236 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultPort = "3306"
+
+	redactedValue = "[REDACTED]"
+)
+
+var (
+	ErrInvalidTLSConfig = errors.New("failed to load mysql tls config")
+	ErrInvalidDSN       = errors.New("failed to build mysql dsn")
+)
+
+// tlsKeys numbers each TLS config registered with the driver, as its registry is shared by every dependency.
+var tlsKeys uint64
+
+// TLSConfig verifies the server against the given CA bundle, rather than the system's roots, and presents the given
+// client certificate, should both CertFile and KeyFile be set.
+type TLSConfig struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// ServerName is verified against the server's certificate, defaulting to the host of each primary or replica.
+	ServerName string
+}
+
+// dsn holds every part of the DSN which may be given via options, each left empty unless given.
+type dsn struct {
+	host         string
+	port         string
+	user         string
+	password     string
+	database     string
+	params       map[string]string
+	dialTimeout  time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	tlsConfig    *TLSConfig
+}
+
+// buildConfig parses the DSN given to New, should there be one, overriding each part of it given via options.
+func (m *MySQL) buildConfig() (*mysql.Config, error) {
+	cfg := mysql.NewConfig()
+
+	if m.addr != "" {
+		parsed, err := mysql.ParseDSN(m.addr)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg = parsed
+	}
+
+	d := m.dsn
+
+	if d.host != "" || d.port != "" {
+		host, port := d.host, d.port
+
+		current, currentPort, err := net.SplitHostPort(cfg.Addr)
+		if err == nil {
+			if host == "" {
+				host = current
+			}
+
+			if port == "" {
+				port = currentPort
+			}
+		}
+
+		if port == "" {
+			port = defaultPort
+		}
+
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, port)
+	}
+
+	if d.user != "" {
+		cfg.User = d.user
+	}
+
+	if d.password != "" {
+		cfg.Passwd = d.password
+	}
+
+	if d.database != "" {
+		cfg.DBName = d.database
+	}
+
+	if d.dialTimeout != 0 {
+		cfg.Timeout = d.dialTimeout
+	}
+
+	if d.readTimeout != 0 {
+		cfg.ReadTimeout = d.readTimeout
+	}
+
+	if d.writeTimeout != 0 {
+		cfg.WriteTimeout = d.writeTimeout
+	}
+
+	if d.tlsConfig != nil {
+		key, err := m.registerTLS()
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.TLSConfig = key
+	}
+
+	if len(d.params) == 0 {
+		return cfg, nil
+	}
+
+	// Params are read as the DSN's own query string, so that the driver's params, such as parseTime, are understood
+	// alongside system variables.
+	parsed, err := mysql.ParseDSN(withParams(cfg.FormatDSN(), d.params))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidDSN)
+	}
+
+	return parsed, nil
+}
+
+// registerTLS registers the TLS config given via WithTLS with the driver, under a key unique to this instance, such
+// that dependencies of the same name do not replace one another's config. It is deregistered on Close. An empty
+// ServerName is left for the driver to default to the host of each connection, so that replicas sharing the
+// config are verified against their own hosts rather than the primary's.
+func (m *MySQL) registerTLS() (string, error) {
+	t := m.dsn.tlsConfig
+
+	tlsCfg := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if t.CAFile != "" {
+		ca, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return "", fmt.Errorf("%v: %w", err, ErrInvalidTLSConfig)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return "", fmt.Errorf("%s holds no certificates: %w", t.CAFile, ErrInvalidTLSConfig)
+		}
+
+		tlsCfg.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("%v: %w", err, ErrInvalidTLSConfig)
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	key := fmt.Sprintf("cgs-%s-%d", m.name, atomic.AddUint64(&tlsKeys, 1))
+
+	err := mysql.RegisterTLSConfig(key, tlsCfg)
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", err, ErrInvalidTLSConfig)
+	}
+
+	m.tlsKey = key
+
+	return key, nil
+}
+
+// deregisterTLS removes the TLS config registered by registerTLS, should there be one, from the driver.
+func (m *MySQL) deregisterTLS() {
+	if m.tlsKey == "" {
+		return
+	}
+
+	mysql.DeregisterTLSConfig(m.tlsKey)
+	m.tlsKey = ""
+}
+
+func withParams(dsn string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+
+	for k := range params {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	b.WriteString(dsn)
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+
+	for _, k := range keys {
+		b.WriteString(sep)
+		b.WriteString(url.QueryEscape(k))
+		b.WriteString("=")
+		b.WriteString(url.QueryEscape(params[k]))
+
+		sep = "&"
+	}
+
+	return b.String()
+}
+
+// redact returns the given DSN with its password replaced, so that it can be logged.
+func redact(cfg *mysql.Config) string {
+	redacted := cfg.Clone()
+	if redacted.Passwd != "" {
+		redacted.Passwd = redactedValue
+	}
+
+	return redacted.FormatDSN()
+}
@@ -0,0 +1,180 @@
+package mysql
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var labels = []string{"name", "operation"}
+
+var (
+	operationCount       *prometheus.CounterVec
+	errorCount           *prometheus.CounterVec
+	duration             *prometheus.HistogramVec
+	txRetryCount         *prometheus.CounterVec
+	replicaHealthy       *prometheus.GaugeVec
+	replicaLagErrorCount *prometheus.CounterVec
+	schemaVersion        *prometheus.GaugeVec
+	pools                *poolCollector
+)
+
+func init() {
+	operationCount = withRate()
+	errorCount = withError()
+	duration = withDuration()
+	txRetryCount = withTxRetry()
+	replicaHealthy = withReplicaHealth()
+	replicaLagErrorCount = withReplicaLagError()
+	schemaVersion = withSchemaVersion()
+	pools = withPools()
+}
+
+func withRate() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mysql_operation_total",
+		Help: "The number of operations",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withError() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mysql_error_total",
+		Help: "The number of those operations that have failed",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withDuration() *prometheus.HistogramVec {
+	d := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "mysql_duration_seconds",
+		Help: "The amount of time those operations take",
+	}, labels)
+
+	prometheus.MustRegister(d)
+
+	return d
+}
+
+func withTxRetry() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mysql_tx_retry_total",
+		Help: "The number of transactions retried, by the reason they failed",
+	}, []string{"name", "reason"})
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withReplicaHealth() *prometheus.GaugeVec {
+	h := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mysql_replica_healthy",
+		Help: "Whether each replica is in rotation, being reachable and within the lag allowed",
+	}, []string{"name"})
+
+	prometheus.MustRegister(h)
+
+	return h
+}
+
+func withReplicaLagError() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mysql_replica_lag_error_total",
+		Help: "The number of times the replication lag of each replica could not be read",
+	}, []string{"name"})
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withSchemaVersion() *prometheus.GaugeVec {
+	v := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mysql_schema_version",
+		Help: "The version of the latest migration applied",
+	}, []string{"name"})
+
+	prometheus.MustRegister(v)
+
+	return v
+}
+
+func withPools() *poolCollector {
+	p := &poolCollector{
+		dbs: make(map[string]*sql.DB),
+		open: prometheus.NewDesc("mysql_open_connections",
+			"The number of established connections, both in use and idle", []string{"name"}, nil),
+		inUse: prometheus.NewDesc("mysql_in_use_connections",
+			"The number of connections currently in use", []string{"name"}, nil),
+		idle: prometheus.NewDesc("mysql_idle_connections",
+			"The number of idle connections", []string{"name"}, nil),
+		waitCount: prometheus.NewDesc("mysql_wait_count_total",
+			"The number of connections waited for", []string{"name"}, nil),
+		waitDuration: prometheus.NewDesc("mysql_wait_duration_seconds_total",
+			"The amount of time spent waiting for new connections", []string{"name"}, nil),
+	}
+
+	prometheus.MustRegister(p)
+
+	return p
+}
+
+// poolCollector exports the connection pool stats of every MySQL dependency, labelled by its name.
+type poolCollector struct {
+	mu           sync.Mutex
+	dbs          map[string]*sql.DB
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+func (p *poolCollector) add(name string, db *sql.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dbs[name] = db
+}
+
+// remove stops exporting the stats of the given pool, unless it has since been replaced by another of the same name.
+func (p *poolCollector) remove(name string, db *sql.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dbs[name] == db {
+		delete(p.dbs, name)
+	}
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.open
+	ch <- p.inUse
+	ch <- p.idle
+	ch <- p.waitCount
+	ch <- p.waitDuration
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, db := range p.dbs {
+		stats := db.Stats()
+
+		ch <- prometheus.MustNewConstMetric(p.open, prometheus.GaugeValue, float64(stats.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(p.inUse, prometheus.GaugeValue, float64(stats.InUse), name)
+		ch <- prometheus.MustNewConstMetric(p.idle, prometheus.GaugeValue, float64(stats.Idle), name)
+		ch <- prometheus.MustNewConstMetric(p.waitCount, prometheus.CounterValue, float64(stats.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(p.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), name)
+	}
+}
@@ -0,0 +1,339 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultMigrationsTable      = "schema_versions"
+	defaultMigrationLockTimeout = time.Second * 60
+	migrationSuffix             = ".up.sql"
+
+	// errNoSuchTable is returned by MySQL when a table does not exist.
+	errNoSuchTable = 1146
+)
+
+var (
+	ErrInvalidMigration       = errors.New("migrations must be named <version>_<name>.up.sql with a unique version")
+	ErrInvalidMigrationsTable = errors.New("migrations table may only contain letters, digits and underscores")
+	ErrMigrationLockTimeout   = errors.New("timed out waiting for the migration lock")
+	ErrMigrationFailed        = errors.New("failed to apply migration")
+	ErrSchemaOutOfDate        = errors.New("schema is not at the latest migration")
+)
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Migration is a single up-migration, read from a file named <version>_<name>.up.sql, such as 0001_create_books.up.sql.
+type Migration struct {
+	Version uint64
+	Name    string
+	SQL     string
+}
+
+// Migrate applies every pending migration in order of version, recording each within the migrations table, and
+// returns those applied. Whilst migrating, an advisory lock is held via GET_LOCK so that only one replica migrates at
+// once, the others waiting for it to finish before finding nothing left to apply.
+//
+// Each migration may hold several statements, each ending in a semicolon at the end of a line. Should a migration
+// fail, those before it remain applied. With WithDryRun, nothing is applied and the pending migrations are returned
+// instead. Without WithMigrations, Migrate does nothing.
+func (m *MySQL) Migrate(ctx context.Context) ([]Migration, error) {
+	if m.migrations == nil {
+		return nil, nil
+	}
+
+	if !tableNamePattern.MatchString(m.migrationsTable) {
+		return nil, fmt.Errorf("%s: %w", m.migrationsTable, ErrInvalidMigrationsTable)
+	}
+
+	migrations, err := readMigrations(m.migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := m.client.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	if m.dryRun {
+		return m.pending(ctx, conn, migrations)
+	}
+
+	release, err := m.lock(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`, m.migrationsTable))
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := m.pending(ctx, conn, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+
+	for _, migration := range pending {
+		err = m.apply(ctx, conn, migration)
+		if err != nil {
+			return applied, fmt.Errorf("%d_%s: %v: %w", migration.Version, migration.Name, err, ErrMigrationFailed)
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// SchemaVersion returns the version of the latest migration applied, which is 0 should none have been. Each version
+// read is exported as mysql_schema_version.
+func (m *MySQL) SchemaVersion(ctx context.Context) (uint64, error) {
+	conn, err := m.client.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer conn.Close()
+
+	version, err := m.schemaVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	schemaVersion.WithLabelValues(m.name).Set(float64(version))
+
+	return version, nil
+}
+
+// CheckSchema fails, reporting the current version, unless every migration given by WithMigrations has been applied.
+// With WithDryRun, the pending migrations are left for another to apply, so an out of date schema is not a failure;
+// its version is still read, and so exported as mysql_schema_version.
+func (m *MySQL) CheckSchema(ctx context.Context) error {
+	if m.migrations == nil {
+		return nil
+	}
+
+	migrations, err := readMigrations(m.migrations)
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	current, err := m.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := migrations[len(migrations)-1].Version
+	if current < latest && !m.dryRun {
+		return fmt.Errorf("at version %d, latest is %d: %w", current, latest, ErrSchemaOutOfDate)
+	}
+
+	return nil
+}
+
+func (m *MySQL) schemaVersion(ctx context.Context, conn *sql.Conn) (uint64, error) {
+	var version uint64
+
+	err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", m.migrationsTable)).
+		Scan(&version)
+	if isNoSuchTable(err) {
+		return 0, nil
+	}
+
+	return version, err
+}
+
+// pending returns every migration which has not been applied.
+func (m *MySQL) pending(ctx context.Context, conn *sql.Conn, migrations []Migration) ([]Migration, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s", m.migrationsTable))
+	if isNoSuchTable(err) {
+		return migrations, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make(map[uint64]bool)
+
+	for rows.Next() {
+		var version uint64
+
+		err = rows.Scan(&version)
+		if err != nil {
+			return nil, err
+		}
+
+		applied[version] = true
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *MySQL) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	for _, statement := range splitStatements(migration.SQL) {
+		_, err := conn.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version, name) VALUES (?, ?)", m.migrationsTable),
+		migration.Version, migration.Name)
+
+	return err
+}
+
+// lock takes the advisory lock of the migrations table within the current database, returning a func to release it.
+func (m *MySQL) lock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	var acquired sql.NullInt64
+
+	// GET_LOCK waits in whole seconds, so the timeout is rounded up lest one under a second not wait at all.
+	timeout := int(math.Ceil(m.migrationLockTimeout.Seconds()))
+
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(CONCAT(DATABASE(), '.', ?), ?)",
+		m.migrationsTable, timeout).Scan(&acquired)
+	if err != nil {
+		return nil, err
+	}
+
+	if acquired.Int64 != 1 {
+		return nil, ErrMigrationLockTimeout
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(CONCAT(DATABASE(), '.', ?))", m.migrationsTable)
+	}, nil
+}
+
+// readMigrations reads every up-migration within the root of fsys, ordered by version. Any other file is ignored.
+func readMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+
+	versions := make(map[uint64]bool)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), migrationSuffix) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimSuffix(entry.Name(), migrationSuffix), "_", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), ErrInvalidMigration)
+		}
+
+		version, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || versions[version] {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), ErrInvalidMigration)
+		}
+
+		versions[version] = true
+
+		b, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    parts[1],
+			SQL:     string(b),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// splitStatements splits a migration into its statements, each of which ends in a semicolon at the end of a line.
+func splitStatements(migration string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+	)
+
+	for _, line := range strings.Split(migration, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if !strings.HasSuffix(strings.TrimSpace(line), ";") {
+			continue
+		}
+
+		statements = appendStatement(statements, current.String())
+		current.Reset()
+	}
+
+	return appendStatement(statements, current.String())
+}
+
+// appendStatement appends the given statement, unless it holds nothing but comments.
+func appendStatement(statements []string, statement string) []string {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") && !strings.HasPrefix(line, "#") {
+			return append(statements, statement)
+		}
+	}
+
+	return statements
+}
+
+func isNoSuchTable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable
+}
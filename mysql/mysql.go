@@ -2,63 +2,152 @@ package mysql
 
 import (
 	"database/sql"
+	"io/fs"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jamieaitken/requestid"
+	"go.uber.org/zap"
 )
 
 const (
+	defaultName = "default"
 	maxLifetime = time.Second * 60
 	maxOpenCons = 0
 	maxIdleCons = 5
 )
 
 type MySQL struct {
+	name        string
 	maxLifetime time.Duration
 	maxOpenCons int
 	maxIdleCons int
 	client      *sql.DB
 	addr        string
+	dsn         dsn
+	cfg         *mysql.Config
+	tlsKey      string
+
+	migrations           fs.FS
+	migrationsTable      string
+	migrationLockTimeout time.Duration
+	dryRun               bool
+
+	txRetries     int
+	txBaseBackoff time.Duration
+
+	replicaAddrs         []string
+	replicaHosts         []string
+	replicas             []*replica
+	selection            Selection
+	next                 uint32
+	maxReplicaLag        time.Duration
+	replicaCheckInterval time.Duration
+	stopChecking         func()
+
+	slowQueryThreshold time.Duration
+	logger             *zap.Logger
+	requestIDKey       requestid.Key
+	queryHooks         []QueryHook
 }
 
 type Option func(*MySQL)
 
 func New(addr string, opts ...Option) (*MySQL, error) {
 	m := &MySQL{
+		name:        defaultName,
 		maxLifetime: maxLifetime,
 		maxIdleCons: maxIdleCons,
 		maxOpenCons: maxOpenCons,
 		addr:        addr,
+
+		migrationsTable:      defaultMigrationsTable,
+		migrationLockTimeout: defaultMigrationLockTimeout,
+
+		txRetries:     defaultTxRetries,
+		txBaseBackoff: defaultTxBackoff,
+
+		selection:            RoundRobin,
+		maxReplicaLag:        defaultMaxReplicaLag,
+		replicaCheckInterval: defaultReplicaCheckInterval,
+
+		logger:       zap.NewNop(),
+		requestIDKey: requestid.DefaultTracingKey,
 	}
 
 	m.add(opts...)
 
-	client, err := sql.Open("mysql", m.addr)
+	cfg, err := m.buildConfig()
 	if err != nil {
+		m.deregisterTLS()
+
 		return nil, err
 	}
 
-	client.SetConnMaxLifetime(m.maxLifetime)
-	client.SetMaxOpenConns(m.maxOpenCons)
-	client.SetMaxIdleConns(m.maxIdleCons)
+	m.cfg = cfg
+
+	client, err := m.open(m.name, cfg)
+	if err != nil {
+		m.deregisterTLS()
+
+		return nil, err
+	}
 
 	m.client = client
 
+	err = m.openReplicas()
+	if err != nil {
+		m.Close()
+
+		return nil, err
+	}
+
 	return m, nil
 }
 
+// open returns a pool of instrumented connections as given by cfg, its metrics labelled by the given name.
+func (m *MySQL) open(name string, cfg *mysql.Config) (*sql.DB, error) {
+	c, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := sql.OpenDB(&connector{Connector: c, observer: &observer{
+		name:          name,
+		slowThreshold: m.slowQueryThreshold,
+		logger:        m.logger,
+		requestIDKey:  m.requestIDKey,
+		hooks:         m.queryHooks,
+	}})
+
+	db.SetConnMaxLifetime(m.maxLifetime)
+	db.SetMaxOpenConns(m.maxOpenCons)
+	db.SetMaxIdleConns(m.maxIdleCons)
+
+	pools.add(name, db)
+
+	return db, nil
+}
+
 func (m *MySQL) add(opts ...Option) {
 	for _, opt := range opts {
 		opt(m)
 	}
 }
 
+// Client returns the primary. See Primary.
 func (m *MySQL) Client() *sql.DB {
 	return m.client
 }
 
+// Name returns the name the metrics of the dependency are labelled by.
+func (m *MySQL) Name() string {
+	return m.name
+}
+
+// Addr returns the DSN connected to, with its password redacted.
 func (m *MySQL) Addr() string {
-	return m.addr
+	return redact(m.cfg)
 }
 
 func (m *MySQL) MaxLifetime() time.Duration {
@@ -72,3 +161,31 @@ func (m *MySQL) MaxIdleCons() int {
 func (m *MySQL) MaxOpenCons() int {
 	return m.maxOpenCons
 }
+
+// SlowQueryThreshold returns the duration beyond which queries are logged, which is 0 should they not be.
+func (m *MySQL) SlowQueryThreshold() time.Duration {
+	return m.slowQueryThreshold
+}
+
+// Migrations returns the migrations given by WithMigrations, if any.
+func (m *MySQL) Migrations() fs.FS {
+	return m.migrations
+}
+
+// DryRun reports whether pending migrations are listed rather than applied.
+func (m *MySQL) DryRun() bool {
+	return m.dryRun
+}
+
+func (m *MySQL) Close() error {
+	m.closeReplicas()
+
+	pools.remove(m.name, m.client)
+	schemaVersion.DeleteLabelValues(m.name)
+
+	err := m.client.Close()
+
+	m.deregisterTLS()
+
+	return err
+}
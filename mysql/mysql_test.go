@@ -1,11 +1,34 @@
 package mysql_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jamieaitken/cgs/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestNew(t *testing.T) {
@@ -59,3 +82,845 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestMySQL_Metrics(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenName         string
+		givenAddr         string
+		expectedMetrics   map[string]float64
+		expectedPoolGauge string
+	}{
+		{
+			name:      "given an unreachable database, expect the failed connection to be counted under the dependency's name",
+			givenName: "metrics-unreachable",
+			givenAddr: "root:hunter@tcp(127.0.0.1:1)/mysql?timeout=1s",
+			expectedMetrics: map[string]float64{
+				"mysql_operation_total": 1,
+				"mysql_error_total":     1,
+			},
+			expectedPoolGauge: "mysql_open_connections",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := mysql.New(test.givenAddr, mysql.WithName(test.givenName))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			err = client.Client().PingContext(context.Background())
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			for name, expected := range test.expectedMetrics {
+				actual := gather(t, name, map[string]string{"name": test.givenName, "operation": "connect"})
+
+				// database/sql retries failed connections, so each attempt is counted.
+				if actual < expected {
+					t.Fatalf("expected %s to be at least %v, got %v", name, expected, actual)
+				}
+			}
+
+			_, ok := find(t, test.expectedPoolGauge, map[string]string{"name": test.givenName})
+			if !ok {
+				t.Fatalf("expected %s for %s", test.expectedPoolGauge, test.givenName)
+			}
+
+			client.Close()
+
+			_, ok = find(t, test.expectedPoolGauge, map[string]string{"name": test.givenName})
+			if ok {
+				t.Fatalf("expected no %s for %s once closed", test.expectedPoolGauge, test.givenName)
+			}
+		})
+	}
+}
+
+// gather returns the value of the counter of the given name with the given labels.
+func gather(t *testing.T, name string, labels map[string]string) float64 {
+	m, ok := find(t, name, labels)
+	if !ok {
+		t.Fatalf("expected %s with labels %v", name, labels)
+	}
+
+	return m.GetCounter().GetValue()
+}
+
+func find(t *testing.T, name string, labels map[string]string) (*dto.Metric, bool) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			matched := 0
+
+			for _, label := range m.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					matched++
+				}
+			}
+
+			if matched == len(labels) {
+				return m, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func TestMySQL_Migrate_Fail(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenOpts     []mysql.Option
+		expectedError error
+	}{
+		{
+			name: "given a migration without a version, expect error",
+			givenOpts: []mysql.Option{mysql.WithMigrations(fstest.MapFS{
+				"create_books.up.sql": {Data: []byte("CREATE TABLE books (id INT);")},
+			})},
+			expectedError: mysql.ErrInvalidMigration,
+		},
+		{
+			name: "given two migrations of the same version, expect error",
+			givenOpts: []mysql.Option{mysql.WithMigrations(fstest.MapFS{
+				"1_create_books.up.sql":   {Data: []byte("CREATE TABLE books (id INT);")},
+				"001_create_music.up.sql": {Data: []byte("CREATE TABLE music (id INT);")},
+			})},
+			expectedError: mysql.ErrInvalidMigration,
+		},
+		{
+			name: "given an invalid migrations table, expect error",
+			givenOpts: []mysql.Option{
+				mysql.WithMigrations(fstest.MapFS{}),
+				mysql.WithMigrationsTable("versions; DROP TABLE books"),
+			},
+			expectedError: mysql.ErrInvalidMigrationsTable,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := mysql.New("root:hunter@tcp(127.0.0.1:1)/mysql?timeout=1s", test.givenOpts...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			_, err = client.Migrate(context.Background())
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestMySQL_CheckSchema(t *testing.T) {
+	migrations := fstest.MapFS{
+		"0001_create_books.up.sql": {Data: []byte("CREATE TABLE books (id INT);")},
+		"0002_create_music.up.sql": {Data: []byte("CREATE TABLE music (id INT);")},
+	}
+
+	tests := []struct {
+		name            string
+		givenName       string
+		givenOpts       []mysql.Option
+		expectedError   error
+		expectedVersion float64
+	}{
+		{
+			name:            "given a pending migration, expect error and the current version exported",
+			givenName:       "schema-pending",
+			givenOpts:       []mysql.Option{mysql.WithMigrations(migrations)},
+			expectedError:   mysql.ErrSchemaOutOfDate,
+			expectedVersion: 1,
+		},
+		{
+			name:            "given a pending migration and a dry run, expect no error and the current version exported",
+			givenName:       "schema-dry-run",
+			givenOpts:       []mysql.Option{mysql.WithMigrations(migrations), mysql.WithDryRun()},
+			expectedVersion: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr, _ := listenMySQL(t, map[string]statusReply{
+				"SELECT COALESCE(MAX(version), 0) FROM schema_versions": {column: "version", value: "1"},
+			})
+
+			client, err := mysql.New(fmt.Sprintf("root:hunter@tcp(%s)/mysql?timeout=1s", addr),
+				append([]mysql.Option{mysql.WithName(test.givenName)}, test.givenOpts...)...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			err = client.CheckSchema(context.Background())
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			m, ok := find(t, "mysql_schema_version", map[string]string{"name": test.givenName})
+			if !ok {
+				t.Fatalf("expected mysql_schema_version for %s", test.givenName)
+			}
+
+			if !cmp.Equal(m.GetGauge().GetValue(), test.expectedVersion) {
+				t.Fatal(cmp.Diff(m.GetGauge().GetValue(), test.expectedVersion))
+			}
+		})
+	}
+}
+
+func TestMySQL_WithinTx_Fail(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenAddr     string
+		expectedCalls int
+	}{
+		{
+			name:          "given the transaction cannot begin, expect error without calling fn",
+			givenAddr:     "root:hunter@tcp(127.0.0.1:1)/mysql?timeout=1s",
+			expectedCalls: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := mysql.New(test.givenAddr)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			calls := 0
+
+			err = client.WithinTx(context.Background(), nil, func(tx *sql.Tx) error {
+				calls++
+
+				return nil
+			})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			if !cmp.Equal(calls, test.expectedCalls) {
+				t.Fatal(cmp.Diff(calls, test.expectedCalls))
+			}
+		})
+	}
+}
+
+func TestMySQL_Replica(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenName         string
+		givenReplicas     []string
+		expectedAddrs     []string
+		expectedUnhealthy []string
+	}{
+		{
+			name:      "given every replica is down, expect the primary to be read from instead",
+			givenName: "replicas-down",
+			givenReplicas: []string{
+				"root:hunter@tcp(127.0.0.1:1)/mysql?timeout=1s",
+				"root:hunter@tcp(127.0.0.1:2)/mysql?timeout=1s",
+			},
+			expectedAddrs: []string{
+				"root:[REDACTED]@tcp(127.0.0.1:1)/mysql?timeout=1s",
+				"root:[REDACTED]@tcp(127.0.0.1:2)/mysql?timeout=1s",
+			},
+			expectedUnhealthy: []string{"replicas-down-replica-0", "replicas-down-replica-1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := mysql.New("root:hunter@tcp(127.0.0.1:1)/mysql?timeout=1s",
+				mysql.WithName(test.givenName),
+				mysql.WithReplicas(test.givenReplicas...),
+				mysql.WithReplicaCheckInterval(time.Millisecond*10),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			if !cmp.Equal(client.ReplicaAddrs(), test.expectedAddrs) {
+				t.Fatal(cmp.Diff(client.ReplicaAddrs(), test.expectedAddrs))
+			}
+
+			deadline := time.Now().Add(time.Second * 5)
+
+			for client.Replica() != client.Primary() {
+				if time.Now().After(deadline) {
+					t.Fatal("expected unhealthy replicas to be taken out of rotation")
+				}
+
+				time.Sleep(time.Millisecond * 10)
+			}
+
+			for _, name := range test.expectedUnhealthy {
+				m, ok := find(t, "mysql_replica_healthy", map[string]string{"name": name})
+				if !ok {
+					t.Fatalf("expected mysql_replica_healthy for %s", name)
+				}
+
+				if m.GetGauge().GetValue() != 0 {
+					t.Fatalf("expected %s to be unhealthy", name)
+				}
+			}
+		})
+	}
+}
+
+func TestMySQL_Replica_Lag(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenName         string
+		givenReplies      map[string]statusReply
+		expectedQueries   []string
+		expectedHealthy   float64
+		expectedLagErrors bool
+	}{
+		{
+			name:      "given a replica lagging beyond the limit, expect it to be taken out of rotation",
+			givenName: "replica-lagging",
+			givenReplies: map[string]statusReply{
+				"SHOW REPLICA STATUS": {column: "Seconds_Behind_Source", value: "60"},
+			},
+			expectedQueries: []string{"SHOW REPLICA STATUS"},
+			expectedHealthy: 0,
+		},
+		{
+			name:      "given a replica which does not understand SHOW REPLICA STATUS, expect the lag read via SHOW SLAVE STATUS",
+			givenName: "replica-legacy",
+			givenReplies: map[string]statusReply{
+				"SHOW REPLICA STATUS": {errNumber: 1064},
+				"SHOW SLAVE STATUS":   {column: "Seconds_Behind_Master", value: "60"},
+			},
+			expectedQueries: []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS", "SHOW SLAVE STATUS"},
+			expectedHealthy: 0,
+		},
+		{
+			name:      "given a replica whose status cannot be read, expect it kept in rotation and the failure counted",
+			givenName: "replica-denied",
+			givenReplies: map[string]statusReply{
+				"SHOW REPLICA STATUS": {errNumber: 1227},
+			},
+			expectedQueries:   []string{"SHOW REPLICA STATUS"},
+			expectedHealthy:   1,
+			expectedLagErrors: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr, queries := listenMySQL(t, test.givenReplies)
+
+			client, err := mysql.New("root:hunter@tcp(127.0.0.1:1)/mysql?timeout=1s",
+				mysql.WithName(test.givenName),
+				mysql.WithReplicas(fmt.Sprintf("root:hunter@tcp(%s)/mysql?timeout=1s", addr)),
+				mysql.WithReplicaCheckInterval(time.Millisecond*10),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			replica := map[string]string{"name": test.givenName + "-replica-0"}
+			deadline := time.Now().Add(time.Second * 5)
+
+			for {
+				healthy, ok := find(t, "mysql_replica_healthy", replica)
+				lagErrors, _ := find(t, "mysql_replica_lag_error_total", replica)
+
+				if len(queries()) >= len(test.expectedQueries) && ok &&
+					healthy.GetGauge().GetValue() == test.expectedHealthy &&
+					(lagErrors.GetCounter().GetValue() > 0) == test.expectedLagErrors {
+					break
+				}
+
+				if time.Now().After(deadline) {
+					t.Fatalf("expected replica health of %v, got queries %v", test.expectedHealthy, queries())
+				}
+
+				time.Sleep(time.Millisecond * 10)
+			}
+
+			actual := queries()[:len(test.expectedQueries)]
+			if !cmp.Equal(actual, test.expectedQueries) {
+				t.Fatal(cmp.Diff(actual, test.expectedQueries))
+			}
+		})
+	}
+}
+
+func TestNew_DSN(t *testing.T) {
+	ca := writeCA(t)
+
+	tests := []struct {
+		name                 string
+		givenAddr            string
+		givenOpts            []mysql.Option
+		expectedAddr         string
+		expectedReplicaAddrs []string
+	}{
+		{
+			name:      "given structured options, expect the dsn built from them with its password redacted",
+			givenAddr: "",
+			givenOpts: []mysql.Option{
+				mysql.WithHost("db.internal"),
+				mysql.WithPort(3307),
+				mysql.WithUser("root"),
+				mysql.WithPassword("hunter"),
+				mysql.WithDatabase("books"),
+				mysql.WithParams(map[string]string{"parseTime": "true", "time_zone": "'+00:00'"}),
+				mysql.WithDialTimeout(time.Second * 5),
+			},
+			expectedAddr: "root:[REDACTED]@tcp(db.internal:3307)/books?parseTime=true&timeout=5s&time_zone=%27%2B00%3A00%27",
+		},
+		{
+			name:         "given a dsn and options, expect the options to take precedence",
+			givenAddr:    "root:hunter@tcp(localhost:3306)/mysql?parseTime=true",
+			givenOpts:    []mysql.Option{mysql.WithDatabase("books"), mysql.WithHost("db.internal")},
+			expectedAddr: "root:[REDACTED]@tcp(db.internal:3306)/books?parseTime=true",
+		},
+		{
+			name:         "given no password, expect nothing to be redacted",
+			givenAddr:    "reader@tcp(localhost:3306)/books",
+			expectedAddr: "reader@tcp(localhost:3306)/books",
+		},
+		{
+			name:      "given a custom ca, expect the tls config to be registered with the driver",
+			givenAddr: "root:hunter@tcp(localhost:3306)/books",
+			givenOpts: []mysql.Option{
+				mysql.WithName("tls-test"),
+				mysql.WithTLS(&mysql.TLSConfig{CAFile: ca}),
+			},
+			expectedAddr: "root:[REDACTED]@tcp(localhost:3306)/books?tls=cgs-tls-test",
+		},
+		{
+			name:         "given replica hosts, expect each connected to as the primary is but for its address",
+			givenAddr:    "root:hunter@tcp(localhost:3306)/books",
+			givenOpts:    []mysql.Option{mysql.WithReplicaHosts("replica-a", "replica-b:3307")},
+			expectedAddr: "root:[REDACTED]@tcp(localhost:3306)/books",
+			expectedReplicaAddrs: []string{
+				"root:[REDACTED]@tcp(replica-a:3306)/books",
+				"root:[REDACTED]@tcp(replica-b:3307)/books",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := mysql.New(test.givenAddr, test.givenOpts...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			actual := tlsKey.ReplaceAllString(client.Addr(), "$1")
+			if !cmp.Equal(actual, test.expectedAddr) {
+				t.Fatal(cmp.Diff(actual, test.expectedAddr))
+			}
+
+			if !cmp.Equal(client.ReplicaAddrs(), test.expectedReplicaAddrs, cmpopts.EquateEmpty()) {
+				t.Fatal(cmp.Diff(client.ReplicaAddrs(), test.expectedReplicaAddrs, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
+
+// tlsKey matches the key TLS configs are registered under, capturing it but for the number unique to each instance.
+var tlsKey = regexp.MustCompile(`(tls=cgs-[\w-]+)-\d+`)
+
+func TestNew_TLS_Deregistered(t *testing.T) {
+	ca := writeCA(t)
+
+	tests := []struct {
+		name      string
+		givenAddr string
+		givenOpts []mysql.Option
+	}{
+		{
+			name:      "given two dependencies of the same name, expect each tls config registered apart until closed",
+			givenAddr: "root:hunter@tcp(localhost:3306)/books",
+			givenOpts: []mysql.Option{mysql.WithTLS(&mysql.TLSConfig{CAFile: ca})},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first, err := mysql.New(test.givenAddr, test.givenOpts...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			second, err := mysql.New(test.givenAddr, test.givenOpts...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer second.Close()
+
+			if first.Addr() == second.Addr() {
+				t.Fatalf("expected distinct tls keys, got %s for both", first.Addr())
+			}
+
+			err = first.Close()
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			_, err = mysqldriver.ParseDSN(first.Addr())
+			if err == nil {
+				t.Fatalf("expected the tls config of %s to be deregistered", first.Addr())
+			}
+
+			_, err = mysqldriver.ParseDSN(second.Addr())
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNew_TLS_ReplicaHosts(t *testing.T) {
+	ca := writeCA(t)
+
+	tests := []struct {
+		name               string
+		givenAddr          string
+		givenTLS           *mysql.TLSConfig
+		expectedServerName string
+	}{
+		{
+			name:               "given no server name, expect each replica to be verified against its own host",
+			givenAddr:          "root:hunter@tcp(primary.invalid:3306)/books",
+			givenTLS:           &mysql.TLSConfig{CAFile: ca},
+			expectedServerName: "localhost",
+		},
+		{
+			name:               "given a server name, expect every replica to be verified against it",
+			givenAddr:          "root:hunter@tcp(primary.invalid:3306)/books",
+			givenTLS:           &mysql.TLSConfig{CAFile: ca, ServerName: "db.internal"},
+			expectedServerName: "db.internal",
+		},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr, serverNames := listenTLS(t)
+
+			client, err := mysql.New(test.givenAddr,
+				mysql.WithName(fmt.Sprintf("tls-replica-test-%d", i)),
+				mysql.WithTLS(test.givenTLS),
+				mysql.WithReplicaHosts(addr),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			defer client.Close()
+
+			go client.Replica().Ping()
+
+			select {
+			case actual := <-serverNames:
+				if !cmp.Equal(actual, test.expectedServerName) {
+					t.Fatal(cmp.Diff(actual, test.expectedServerName))
+				}
+			case <-time.After(time.Second * 5):
+				t.Fatal("expected a tls handshake with the replica")
+			}
+		})
+	}
+}
+
+func TestNew_DSN_Fail(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenOpts     []mysql.Option
+		expectedError error
+	}{
+		{
+			name:          "given a missing ca, expect error",
+			givenOpts:     []mysql.Option{mysql.WithTLS(&mysql.TLSConfig{CAFile: "missing.crt"})},
+			expectedError: mysql.ErrInvalidTLSConfig,
+		},
+		{
+			name:          "given a ca file holding no certificates, expect error",
+			givenOpts:     []mysql.Option{mysql.WithTLS(&mysql.TLSConfig{CAFile: "mysql_test.go"})},
+			expectedError: mysql.ErrInvalidTLSConfig,
+		},
+		{
+			name:          "given an unknown driver param, expect error",
+			givenOpts:     []mysql.Option{mysql.WithParams(map[string]string{"parseTime": "maybe"})},
+			expectedError: mysql.ErrInvalidDSN,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := mysql.New("root:hunter@tcp(localhost:3306)/books", test.givenOpts...)
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+// writeCA writes a self-signed CA certificate, returning its path.
+func writeCA(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cgs-test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "ca.crt")
+
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	return file
+}
+
+// listenTLS listens on localhost as a MySQL server which only offers TLS, sending the server name each client gives
+// as it starts its TLS handshake, before the handshake is abandoned.
+func listenTLS(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	serverNames := make(chan string, 1)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				// The greeting offers CLIENT_PROTOCOL_41, CLIENT_SSL, CLIENT_SECURE_CONNECTION and CLIENT_PLUGIN_AUTH.
+				greeting := []byte{0x0a}
+				greeting = append(greeting, "5.7.0\x00"...)
+				greeting = append(greeting, 1, 0, 0, 0)
+				greeting = append(greeting, "abcdefgh\x00"...)
+				greeting = append(greeting, 0x01, 0x8a, 0x21, 0x02, 0x00, 0x08, 0x00, 21)
+				greeting = append(greeting, make([]byte, 10)...)
+				greeting = append(greeting, "ijklmnopqrst\x00"...)
+				greeting = append(greeting, "mysql_native_password\x00"...)
+
+				_, err := conn.Write(append([]byte{byte(len(greeting)), 0, 0, 0}, greeting...))
+				if err != nil {
+					return
+				}
+
+				// The client asks to switch to TLS via a 32 byte packet, following its 4 byte header.
+				_, err = io.ReadFull(conn, make([]byte, 36))
+				if err != nil {
+					return
+				}
+
+				tls.Server(conn, &tls.Config{
+					GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+						select {
+						case serverNames <- hello.ServerName:
+						default:
+						}
+
+						return nil, errors.New("handshake abandoned")
+					},
+				}).Handshake()
+			}()
+		}
+	}()
+
+	// The listener is addressed by name, as no server name is sent for an IP address.
+	return fmt.Sprintf("localhost:%d", l.Addr().(*net.TCPAddr).Port), serverNames
+}
+
+// statusReply is how the server given by listenMySQL replies to a query, either failing with the given error number
+// or returning a single row holding the given value within the given column.
+type statusReply struct {
+	errNumber uint16
+	column    string
+	value     string
+}
+
+// listenMySQL starts a MySQL server which accepts any credentials, replies to each query as given and records them.
+func listenMySQL(t *testing.T, replies map[string]statusReply) (string, func() []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				// The greeting offers CLIENT_PROTOCOL_41, CLIENT_SECURE_CONNECTION and CLIENT_PLUGIN_AUTH.
+				greeting := []byte{0x0a}
+				greeting = append(greeting, "5.7.0\x00"...)
+				greeting = append(greeting, 1, 0, 0, 0)
+				greeting = append(greeting, "abcdefgh\x00"...)
+				greeting = append(greeting, 0x01, 0x82, 0x21, 0x02, 0x00, 0x08, 0x00, 21)
+				greeting = append(greeting, make([]byte, 10)...)
+				greeting = append(greeting, "ijklmnopqrst\x00"...)
+				greeting = append(greeting, "mysql_native_password\x00"...)
+
+				if writePacket(conn, 0, greeting) != nil {
+					return
+				}
+
+				_, err := readPacket(conn)
+				if err != nil || writePacket(conn, 2, okPacket()) != nil {
+					return
+				}
+
+				for {
+					command, err := readPacket(conn)
+					if err != nil || len(command) == 0 {
+						return
+					}
+
+					switch command[0] {
+					case 0x03:
+						query := string(command[1:])
+
+						mu.Lock()
+						queries = append(queries, query)
+						mu.Unlock()
+
+						err = writeStatus(conn, replies[query])
+					case 0x0e:
+						err = writePacket(conn, 1, okPacket())
+					default:
+						return
+					}
+
+					if err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), queries...)
+	}
+}
+
+// writeStatus writes the given reply, as a result set of one column and one row, unless it is an error.
+func writeStatus(conn net.Conn, reply statusReply) error {
+	if reply.errNumber != 0 {
+		packet := []byte{0xff, byte(reply.errNumber), byte(reply.errNumber >> 8)}
+		packet = append(packet, "#42000failed"...)
+
+		return writePacket(conn, 1, packet)
+	}
+
+	column := []byte{}
+	for _, s := range []string{"def", "", "", "", reply.column, reply.column} {
+		column = append(column, byte(len(s)))
+		column = append(column, s...)
+	}
+
+	// The fixed length fields hold the charset, length, type of VAR_STRING, flags and decimals.
+	column = append(column, 0x0c, 0x21, 0x00, 0xff, 0x00, 0x00, 0x00, 0xfd, 0x00, 0x00, 0x00, 0x00, 0x00)
+
+	eof := []byte{0xfe, 0x00, 0x00, 0x02, 0x00}
+	row := append([]byte{byte(len(reply.value))}, reply.value...)
+
+	for i, packet := range [][]byte{{0x01}, column, eof, row, eof} {
+		err := writePacket(conn, byte(i+1), packet)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func okPacket() []byte {
+	return []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}
+}
+
+func writePacket(conn net.Conn, seq byte, payload []byte) error {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}
+
+	_, err := conn.Write(append(header, payload...))
+
+	return err
+}
+
+func readPacket(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 4)
+
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+
+	_, err = io.ReadFull(conn, payload)
+
+	return payload, err
+}
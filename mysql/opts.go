@@ -1,6 +1,21 @@
 package mysql
 
-import "time"
+import (
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/jamieaitken/requestid"
+	"go.uber.org/zap"
+)
+
+// WithName sets the name the metrics of the dependency are labelled by. cgs.WithMySQL sets it to the name the
+// dependency is registered under.
+func WithName(name string) Option {
+	return func(sql *MySQL) {
+		sql.name = name
+	}
+}
 
 func WithMaxLifetime(duration time.Duration) Option {
 	return func(sql *MySQL) {
@@ -19,3 +34,195 @@ func WithMaxIdleConnections(connections int) Option {
 		sql.maxIdleCons = connections
 	}
 }
+
+// WithMigrations applies every pending up-migration within the root of fsys, such as an embedded directory, once the
+// dependency is registered via cgs.WithMySQL. See MySQL.Migrate.
+func WithMigrations(fsys fs.FS) Option {
+	return func(sql *MySQL) {
+		sql.migrations = fsys
+	}
+}
+
+// WithMigrationsTable sets the table applied migrations are recorded within, which is schema_versions by default.
+func WithMigrationsTable(table string) Option {
+	return func(sql *MySQL) {
+		sql.migrationsTable = table
+	}
+}
+
+// WithMigrationLockTimeout sets how long to wait for another replica to finish migrating, which is 60s by default.
+func WithMigrationLockTimeout(timeout time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.migrationLockTimeout = timeout
+	}
+}
+
+// WithDryRun lists pending migrations rather than applying them. CheckSchema then no longer fails whilst any are
+// pending.
+func WithDryRun() Option {
+	return func(sql *MySQL) {
+		sql.dryRun = true
+	}
+}
+
+// WithTxRetries sets how many times WithinTx retries a transaction which deadlocked or timed out waiting for a lock,
+// which is 3 by default. Given 0, transactions are never retried.
+func WithTxRetries(retries int) Option {
+	return func(sql *MySQL) {
+		sql.txRetries = retries
+	}
+}
+
+// WithTxBackoff sets the backoff before WithinTx first retries a transaction, which is 50ms by default. It doubles for
+// every retry, up to 1s, and is jittered.
+func WithTxBackoff(backoff time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.txBaseBackoff = backoff
+	}
+}
+
+// WithReplicas opens a pool to each of the given replicas, alongside the primary, to be read from via Replica.
+func WithReplicas(addrs ...string) Option {
+	return func(sql *MySQL) {
+		sql.replicaAddrs = append(sql.replicaAddrs, addrs...)
+	}
+}
+
+// WithReplicaSelection sets how Replica chooses between healthy replicas, which is RoundRobin by default.
+func WithReplicaSelection(selection Selection) Option {
+	return func(sql *MySQL) {
+		sql.selection = selection
+	}
+}
+
+// WithMaxReplicaLag sets how far a replica may lag behind the primary before it is taken out of rotation, which is
+// 30s by default.
+func WithMaxReplicaLag(lag time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.maxReplicaLag = lag
+	}
+}
+
+// WithReplicaCheckInterval sets how often the health of each replica is checked, which is every 5s by default.
+func WithReplicaCheckInterval(interval time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.replicaCheckInterval = interval
+	}
+}
+
+// WithHost sets the host connected to, over any given by the DSN.
+func WithHost(host string) Option {
+	return func(sql *MySQL) {
+		sql.dsn.host = host
+	}
+}
+
+// WithPort sets the port connected to, over any given by the DSN. It is 3306 by default.
+func WithPort(port int) Option {
+	return func(sql *MySQL) {
+		sql.dsn.port = strconv.Itoa(port)
+	}
+}
+
+// WithUser sets the user connected as, over any given by the DSN.
+func WithUser(user string) Option {
+	return func(sql *MySQL) {
+		sql.dsn.user = user
+	}
+}
+
+// WithPassword sets the password of the user, over any given by the DSN. It is redacted from Addr.
+func WithPassword(password string) Option {
+	return func(sql *MySQL) {
+		sql.dsn.password = password
+	}
+}
+
+// WithDatabase sets the database connected to, over any given by the DSN.
+func WithDatabase(database string) Option {
+	return func(sql *MySQL) {
+		sql.dsn.database = database
+	}
+}
+
+// WithParams adds the given params to the DSN, such as parseTime, as well as any system variables to be set upon
+// connecting.
+func WithParams(params map[string]string) Option {
+	return func(sql *MySQL) {
+		if sql.dsn.params == nil {
+			sql.dsn.params = make(map[string]string, len(params))
+		}
+
+		for k, v := range params {
+			sql.dsn.params[k] = v
+		}
+	}
+}
+
+// WithDialTimeout sets how long to wait for a connection to be established.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.dsn.dialTimeout = timeout
+	}
+}
+
+// WithReadTimeout sets how long to wait for each read from a connection.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.dsn.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets how long to wait for each write to a connection.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.dsn.writeTimeout = timeout
+	}
+}
+
+// WithTLS connects over TLS as given by conf, which is registered with the driver on New.
+func WithTLS(conf *TLSConfig) Option {
+	return func(sql *MySQL) {
+		sql.dsn.tlsConfig = conf
+	}
+}
+
+// WithReplicaHosts adds a replica for each of the given hosts, optionally with a port, each connected to exactly as the
+// primary is but for its address.
+func WithReplicaHosts(hosts ...string) Option {
+	return func(sql *MySQL) {
+		sql.replicaHosts = append(sql.replicaHosts, hosts...)
+	}
+}
+
+// WithLogger sets the logger slow queries, and failures to read the replication lag of replicas, are logged to.
+// cgs.WithMySQL sets it to the application's logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(sql *MySQL) {
+		sql.logger = logger
+	}
+}
+
+// WithSlowQueryThreshold logs every query taking at least the given duration as a warning, with its SQL, duration and
+// the request ID within its context, should there be one. Args are always redacted. By default, no query is logged.
+func WithSlowQueryThreshold(threshold time.Duration) Option {
+	return func(sql *MySQL) {
+		sql.slowQueryThreshold = threshold
+	}
+}
+
+// WithRequestIDKey sets the context key the request ID of slow queries is read from, which must match the key given
+// to the router's tracer. Defaults to requestid.DefaultTracingKey.
+func WithRequestIDKey(key requestid.Key) Option {
+	return func(sql *MySQL) {
+		sql.requestIDKey = key
+	}
+}
+
+// WithQueryHook calls the given hook once each query completes, such as to trace it. Hooks are called in the order
+// given.
+func WithQueryHook(hook QueryHook) Option {
+	return func(sql *MySQL) {
+		sql.queryHooks = append(sql.queryHooks, hook)
+	}
+}
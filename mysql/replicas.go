@@ -0,0 +1,333 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxReplicaLag        = time.Second * 30
+	defaultReplicaCheckInterval = time.Second * 5
+
+	// replicaStatus and secondsBehindSource read the replication lag from MySQL 8.0.22 onwards, which is NULL should
+	// replication have stopped.
+	replicaStatus       = "SHOW REPLICA STATUS"
+	secondsBehindSource = "Seconds_Behind_Source"
+
+	// slaveStatus and secondsBehindMaster read the replication lag from servers prior to MySQL 8.0.22 and MariaDB.
+	slaveStatus         = "SHOW SLAVE STATUS"
+	secondsBehindMaster = "Seconds_Behind_Master"
+
+	// errParseError is the number of the error returned for a statement the server does not understand.
+	errParseError = 1064
+)
+
+// Selection is how Replica chooses between healthy replicas.
+type Selection int
+
+const (
+	// RoundRobin takes each healthy replica in turn.
+	RoundRobin Selection = iota
+	// LeastConnections takes the healthy replica with the fewest connections in use.
+	LeastConnections
+)
+
+type replica struct {
+	name    string
+	cfg     *mysql.Config
+	db      *sql.DB
+	healthy int32
+	// legacy is set once the replica has rejected SHOW REPLICA STATUS. It is only used by checkReplicas.
+	legacy bool
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func (r *replica) setHealthy(healthy bool) {
+	var v int32
+	if healthy {
+		v = 1
+	}
+
+	replicaHealthy.WithLabelValues(r.name).Set(float64(v))
+
+	atomic.StoreInt32(&r.healthy, v)
+}
+
+// Primary returns the primary, which every write must be made to.
+func (m *MySQL) Primary() *sql.DB {
+	return m.client
+}
+
+// Replica returns a healthy replica, chosen as given by WithReplicaSelection. Should there be no replicas, or none be
+// healthy, the primary is returned instead, so reads carry on regardless.
+func (m *MySQL) Replica() *sql.DB {
+	var healthy []*replica
+
+	for _, r := range m.replicas {
+		if r.isHealthy() {
+			healthy = append(healthy, r)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return m.client
+	}
+
+	if m.selection == LeastConnections {
+		least := healthy[0]
+
+		for _, r := range healthy[1:] {
+			if r.db.Stats().InUse < least.db.Stats().InUse {
+				least = r
+			}
+		}
+
+		return least.db
+	}
+
+	next := atomic.AddUint32(&m.next, 1) - 1
+
+	return healthy[int(next%uint32(len(healthy)))].db
+}
+
+// ReplicaAddrs returns the DSN of every replica, with its password redacted.
+func (m *MySQL) ReplicaAddrs() []string {
+	addrs := make([]string, 0, len(m.replicas))
+
+	for _, r := range m.replicas {
+		addrs = append(addrs, redact(r.cfg))
+	}
+
+	return addrs
+}
+
+// openReplicas opens a pool to each replica, each labelled as <name>-replica-<index>, and starts checking their
+// health.
+func (m *MySQL) openReplicas() error {
+	cfgs, err := m.replicaConfigs()
+	if err != nil {
+		return err
+	}
+
+	if len(cfgs) == 0 {
+		return nil
+	}
+
+	for i, cfg := range cfgs {
+		name := fmt.Sprintf("%s-replica-%d", m.name, i)
+
+		db, err := m.open(name, cfg)
+		if err != nil {
+			return fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		r := &replica{name: name, cfg: cfg, db: db}
+		r.setHealthy(true)
+
+		m.replicas = append(m.replicas, r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	m.stopChecking = func() {
+		cancel()
+		<-done
+	}
+
+	go func() {
+		defer close(done)
+
+		m.checkReplicas(ctx)
+	}()
+
+	return nil
+}
+
+// replicaConfigs parses each DSN given by WithReplicas, followed by the primary's config pointed at each host given by
+// WithReplicaHosts.
+func (m *MySQL) replicaConfigs() ([]*mysql.Config, error) {
+	var cfgs []*mysql.Config
+
+	for i, addr := range m.replicaAddrs {
+		cfg, err := mysql.ParseDSN(addr)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		cfgs = append(cfgs, cfg)
+	}
+
+	for _, host := range m.replicaHosts {
+		_, _, err := net.SplitHostPort(host)
+		if err != nil {
+			host = net.JoinHostPort(host, defaultPort)
+		}
+
+		cfg := m.cfg.Clone()
+		cfg.Net = "tcp"
+		cfg.Addr = host
+
+		cfgs = append(cfgs, cfg)
+	}
+
+	return cfgs, nil
+}
+
+// checkReplicas checks every replica until stopped, taking those which are down or lagging out of rotation until they
+// recover.
+func (m *MySQL) checkReplicas(ctx context.Context) {
+	ticker := time.NewTicker(m.replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, r := range m.replicas {
+			checkCtx, cancel := context.WithTimeout(ctx, m.replicaCheckInterval)
+			err := m.checkReplica(checkCtx, r)
+			cancel()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			r.setHealthy(err == nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReplica fails should the replica be unreachable, its replication have stopped or its lag exceed the limit
+// given by WithMaxReplicaLag. Replicas whose replication status cannot be read are only required to be reachable, with
+// each failure to read it logged and counted by mysql_replica_lag_error_total.
+func (m *MySQL) checkReplica(ctx context.Context, r *replica) error {
+	err := r.db.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	lag, ok, err := r.replicationLag(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			replicaLagErrorCount.WithLabelValues(r.name).Inc()
+			m.logger.Warn("failed to read replication lag", zap.String("name", r.name), zap.Error(err))
+		}
+
+		return nil
+	}
+
+	if !ok {
+		return nil
+	}
+
+	if lag == nil {
+		return fmt.Errorf("%s: replication stopped", r.name)
+	}
+
+	if *lag > m.maxReplicaLag {
+		return fmt.Errorf("%s: lagging by %s", r.name, *lag)
+	}
+
+	return nil
+}
+
+// replicationLag reads Seconds_Behind_Source from SHOW REPLICA STATUS, falling back to Seconds_Behind_Master from SHOW
+// SLAVE STATUS for servers which do not understand the former. Should the status be empty, as it is for servers which
+// are not replicas, false is returned. A nil lag means replication has stopped.
+func (r *replica) replicationLag(ctx context.Context) (*time.Duration, bool, error) {
+	if !r.legacy {
+		lag, ok, err := readLag(ctx, r.db, replicaStatus, secondsBehindSource)
+
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr.Number != errParseError {
+			return lag, ok, err
+		}
+
+		r.legacy = true
+	}
+
+	return readLag(ctx, r.db, slaveStatus, secondsBehindMaster)
+}
+
+// readLag reads the lag, in seconds, from the given column of the status returned by the given statement.
+func readLag(ctx context.Context, db *sql.DB, statement, column string) (*time.Duration, bool, error) {
+	rows, err := db.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !rows.Next() {
+		return nil, false, rows.Err()
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	err = rows.Scan(dest...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for i, c := range columns {
+		if c != column {
+			continue
+		}
+
+		if values[i] == nil {
+			return nil, true, nil
+		}
+
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return nil, false, err
+		}
+
+		lag := time.Duration(seconds) * time.Second
+
+		return &lag, true, nil
+	}
+
+	return nil, false, nil
+}
+
+func (m *MySQL) closeReplicas() {
+	if m.stopChecking != nil {
+		m.stopChecking()
+		m.stopChecking = nil
+	}
+
+	for _, r := range m.replicas {
+		pools.remove(r.name, r.db)
+		replicaHealthy.DeleteLabelValues(r.name)
+		replicaLagErrorCount.DeleteLabelValues(r.name)
+
+		r.db.Close()
+	}
+}
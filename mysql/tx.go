@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultTxRetries    = 3
+	defaultTxBackoff    = time.Millisecond * 50
+	defaultTxMaxBackoff = time.Second
+
+	// errDeadlock and errLockWaitTimeout are returned by MySQL when a transaction must be retried.
+	errDeadlock        = 1213
+	errLockWaitTimeout = 1205
+
+	reasonDeadlock        = "deadlock"
+	reasonLockWaitTimeout = "lock_wait_timeout"
+)
+
+var ErrTxPanicked = errors.New("transaction panicked")
+
+// WithinTx runs fn within a transaction, committing it should fn succeed and rolling it back otherwise. A panic
+// within fn rolls the transaction back and is returned as an error wrapping ErrTxPanicked.
+//
+// Should the transaction fail with a deadlock (1213) or a lock wait timeout (1205), whether within fn or on commit, it
+// is rolled back and fn is run again within a new transaction, after a jittered backoff, up to the limit given by
+// WithTxRetries. fn may therefore be run several times, so it should have no side effects outside the transaction.
+func (m *MySQL) WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		err = m.runTx(ctx, opts, fn)
+
+		reason, retryable := retryReason(err)
+		if !retryable || attempt >= m.txRetries {
+			break
+		}
+
+		txRetryCount.WithLabelValues(m.name, reason).Inc()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.txBackoff(attempt)):
+		}
+	}
+
+	if _, retryable := retryReason(err); retryable && m.txRetries > 0 {
+		return fmt.Errorf("after %d retries: %w", m.txRetries, err)
+	}
+
+	return err
+}
+
+func (m *MySQL) runTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := m.client.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = fmt.Errorf("%v: %w", r, ErrTxPanicked)
+		}
+
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// txBackoff returns a random duration of up to the backoff, doubled for every attempt made, so that transactions which
+// deadlocked one another do not retry in lockstep.
+func (m *MySQL) txBackoff(attempt int) time.Duration {
+	backoff := m.txBaseBackoff << attempt
+	if backoff <= 0 || backoff > defaultTxMaxBackoff {
+		backoff = defaultTxMaxBackoff
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+func retryReason(err error) (string, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return "", false
+	}
+
+	switch mysqlErr.Number {
+	case errDeadlock:
+		return reasonDeadlock, true
+	case errLockWaitTimeout:
+		return reasonLockWaitTimeout, true
+	default:
+		return "", false
+	}
+}
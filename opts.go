@@ -3,17 +3,20 @@ package cgs
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/jamieaitken/cgs/config"
 	"github.com/jamieaitken/cgs/mysql"
+	"github.com/jamieaitken/cgs/postgres"
 	"github.com/jamieaitken/cgs/publisher"
 	"github.com/jamieaitken/cgs/redis"
 	"github.com/jamieaitken/cgs/router"
 	"github.com/jamieaitken/cgs/server"
+	"github.com/jamieaitken/cgs/stream"
 	"github.com/jamieaitken/cgs/subscriber"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -21,27 +24,77 @@ const (
 )
 
 func WithLoggerOpts(opts ...zap.Option) Option {
-	return func(application *Application) {
+	return func(application *Application) error {
 		l, err := zap.NewProduction(opts...)
 		if err != nil {
-			application.logger.Error("failed to apply new logger", zap.Error(err))
-			return
+			return newOptionError("logger", "", err)
 		}
 
 		application.logger = l
+
+		return nil
+	}
+}
+
+// WithShutdownTimeout sets how long Run waits for every dependency to shut down once its context is cancelled.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(application *Application) error {
+		application.shutdownTimeout = timeout
+
+		return nil
+	}
+}
+
+// WithSignals sets which signals cause Run to shut the application down, replacing the default of SIGINT and
+// SIGTERM. Given no signals, Run will not listen for any.
+func WithSignals(signals ...os.Signal) Option {
+	return func(application *Application) error {
+		application.signals = signals
+
+		return nil
+	}
+}
+
+// WithOnStart registers a Hook to be run, in the order registered, before Run starts its functions. Should one fail,
+// none of the functions are started and the application is shut down.
+func WithOnStart(hook Hook) Option {
+	return func(application *Application) error {
+		application.onStart = append(application.onStart, hook)
+
+		return nil
+	}
+}
+
+// WithOnStop registers a Hook to be run, in the order registered, once the application begins to shut down but
+// before the server is drained and any dependency is closed.
+func WithOnStop(hook Hook) Option {
+	return func(application *Application) error {
+		application.onStop = append(application.onStop, hook)
+
+		return nil
+	}
+}
+
+// WithHookTimeout sets how long each OnStart and OnStop hook has to complete.
+func WithHookTimeout(timeout time.Duration) Option {
+	return func(application *Application) error {
+		application.hookTimeout = timeout
+
+		return nil
 	}
 }
 
 func WithRedis(ctx context.Context, name string, addrs []string, opts ...redis.Option) Option {
-	return func(application *Application) {
+	return func(application *Application) error {
 		application.mu.Lock()
 		defer application.mu.Unlock()
 
-		r := redis.New(addrs, opts...)
+		r := redis.New(addrs, append([]redis.Option{redis.WithName(name)}, opts...)...)
 		application.redis[name] = r
+		application.register(kindRedis, name, r.Close)
 
 		application.health.AddReadinessCheck(fmt.Sprintf("%s-redis", name), func() error {
-			err := r.Client().Ping(ctx, "application").Err()
+			err := r.Ping(ctx)
 			if err != nil {
 				application.logger.Error(fmt.Sprintf("%s-redis failed healthcheck", name), zap.Error(err))
 			}
@@ -49,47 +102,134 @@ func WithRedis(ctx context.Context, name string, addrs []string, opts ...redis.O
 			return err
 		})
 		application.logger.Info(fmt.Sprintf(registeredMsg, name, "redis"))
+
+		return nil
 	}
 }
 
+// WithMySQL registers a MySQL pool, applying its migrations, should there be any, before it is registered. As migrating
+// may wait upon another replica's migration lock, the pool is opened and migrated without holding up other options.
 func WithMySQL(ctx context.Context, name, addr string, opts ...mysql.Option) Option {
-	return func(application *Application) {
+	return func(application *Application) error {
 		application.mu.Lock()
-		defer application.mu.Unlock()
+		logger := application.logger
+		application.mu.Unlock()
 
-		m, err := mysql.New(addr, opts...)
+		defaults := []mysql.Option{mysql.WithName(name), mysql.WithLogger(logger)}
+
+		m, err := mysql.New(addr, append(defaults, opts...)...)
+		if err != nil {
+			return newOptionError(kindMySQL, name, err)
+		}
+
+		err = migrateMySQL(ctx, logger, name, m)
 		if err != nil {
-			application.logger.Error(fmt.Sprintf("failed to create sql client for %s", name), zap.Error(err))
-			return
+			m.Close()
+
+			return newOptionError(kindMySQL, name, err)
 		}
 
+		application.mu.Lock()
+		defer application.mu.Unlock()
+
 		application.mysql[name] = m
+		application.register(kindMySQL, name, m.Close)
 		application.health.AddReadinessCheck(fmt.Sprintf("%s-mysql", name), func() error {
-			err = m.Client().PingContext(ctx)
+			err := m.Client().PingContext(ctx)
 			if err != nil {
 				application.logger.Error(fmt.Sprintf("%s-mysql failed healthcheck", name), zap.Error(err))
 			}
 
 			return err
 		})
+		if m.Migrations() != nil {
+			application.health.AddReadinessCheck(fmt.Sprintf("%s-mysql-schema", name), func() error {
+				err := m.CheckSchema(ctx)
+				if err != nil {
+					application.logger.Error(fmt.Sprintf("%s-mysql failed schema check", name), zap.Error(err))
+				}
+
+				return err
+			})
+		}
 		application.logger.Info(fmt.Sprintf(registeredMsg, name, "mysql"))
+
+		return nil
+	}
+}
+
+// migrateMySQL applies, or with mysql.WithDryRun lists, every pending migration of the given MySQL.
+func migrateMySQL(ctx context.Context, logger *zap.Logger, name string, m *mysql.MySQL) error {
+	if m.Migrations() == nil {
+		return nil
+	}
+
+	migrations, err := m.Migrate(ctx)
+
+	msg := "%s-mysql applied migration %d_%s"
+	if m.DryRun() {
+		msg = "%s-mysql has pending migration %d_%s"
+	}
+
+	for _, migration := range migrations {
+		logger.Info(fmt.Sprintf(msg, name, migration.Version, migration.Name))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, err := m.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("%s-mysql schema at version %d", name, version))
+
+	return nil
+}
+
+// WithPostgres registers a Postgres pool connected to via the given DSN, which may be a URL or key/value pairs.
+func WithPostgres(ctx context.Context, name, addr string, opts ...postgres.Option) Option {
+	return func(application *Application) error {
+		application.mu.Lock()
+		defer application.mu.Unlock()
+
+		p, err := postgres.New(addr, append([]postgres.Option{postgres.WithName(name)}, opts...)...)
+		if err != nil {
+			return newOptionError(kindPostgres, name, err)
+		}
+
+		application.postgres[name] = p
+		application.register(kindPostgres, name, p.Close)
+		application.health.AddReadinessCheck(fmt.Sprintf("%s-postgres", name), func() error {
+			err := p.Client().PingContext(ctx)
+			if err != nil {
+				application.logger.Error(fmt.Sprintf("%s-postgres failed healthcheck", name), zap.Error(err))
+			}
+
+			return err
+		})
+		application.logger.Info(fmt.Sprintf(registeredMsg, name, "postgres"))
+
+		return nil
 	}
 }
 
 func WithPublisher(ctx context.Context, name string, addrs []string, topic string, opts ...publisher.Option) Option {
-	return func(application *Application) {
+	return func(application *Application) error {
 		application.mu.Lock()
 		defer application.mu.Unlock()
 
 		p, err := publisher.New(addrs, topic, opts...)
 		if err != nil {
-			application.logger.Error(fmt.Sprintf("failed to create kafka publisher for %s", name), zap.Error(err))
-			return
+			return newOptionError(kindPublisher, name, err)
 		}
 
 		application.publishers[name] = p
+		application.register(kindPublisher, name, p.Close)
 		application.health.AddReadinessCheck(fmt.Sprintf("%s-publisher", name), func() error {
-			err = p.Ping(ctx)
+			err := p.Ping(ctx)
 			if err != nil {
 				application.logger.Error(fmt.Sprintf("%s-publisher failed healthcheck", name), zap.Error(err))
 			}
@@ -97,23 +237,25 @@ func WithPublisher(ctx context.Context, name string, addrs []string, topic strin
 			return err
 		})
 		application.logger.Info(fmt.Sprintf(registeredMsg, name, "publisher"))
+
+		return nil
 	}
 }
 
 func WithSubscriber(ctx context.Context, name string, addrs []string, topic string, opts ...subscriber.Option) Option {
-	return func(application *Application) {
+	return func(application *Application) error {
 		application.mu.Lock()
 		defer application.mu.Unlock()
 
 		p, err := subscriber.New(addrs, topic, opts...)
 		if err != nil {
-			application.logger.Error(fmt.Sprintf("failed to create kafka subscriber for %s", name), zap.Error(err))
-			return
+			return newOptionError(kindSubscriber, name, err)
 		}
 
 		application.subscribers[name] = p
+		application.register(kindSubscriber, name, p.Close)
 		application.health.AddReadinessCheck(fmt.Sprintf("%s-subscriber", name), func() error {
-			err = p.Ping(ctx)
+			err := p.Ping(ctx)
 			if err != nil {
 				application.logger.Error(fmt.Sprintf("%s-subscriber failed healthcheck", name), zap.Error(err))
 			}
@@ -121,48 +263,99 @@ func WithSubscriber(ctx context.Context, name string, addrs []string, topic stri
 			return err
 		})
 		application.logger.Info(fmt.Sprintf(registeredMsg, name, "subscriber"))
+
+		return nil
+	}
+}
+
+// WithRedisStream registers a consumer of the given stream within the given group, read via the Redis dependency
+// registered under redisName, which must be registered first. The group is created straight away, should it not
+// exist, whilst messages are only handled once the consumer's Run is given to Application.Run.
+func WithRedisStream(ctx context.Context, name, redisName, key, group string, h stream.Handler, opts ...stream.Option) Option {
+	return func(application *Application) error {
+		application.mu.Lock()
+		defer application.mu.Unlock()
+
+		r, ok := application.redis[redisName]
+		if !ok {
+			return newOptionError(kindStream, name, fmt.Errorf("%s: %w", redisName, ErrInvalidRedisClient))
+		}
+
+		c := stream.New(r, key, group, h, append([]stream.Option{stream.WithLogger(application.logger)}, opts...)...)
+
+		err := c.CreateGroup(ctx)
+		if err != nil {
+			return newOptionError(kindStream, name, err)
+		}
+
+		application.streams[name] = c
+		application.register(kindStream, name, c.Close)
+		application.health.AddReadinessCheck(fmt.Sprintf("%s-stream", name), func() error {
+			err := c.Ping(ctx)
+			if err != nil {
+				application.logger.Error(fmt.Sprintf("%s-stream failed healthcheck", name), zap.Error(err))
+			}
+
+			return err
+		})
+		application.logger.Info(fmt.Sprintf(registeredMsg, name, "stream"))
+
+		return nil
 	}
 }
 
 func WithRouter(opts ...router.Option) Option {
-	return func(application *Application) {
+	return func(application *Application) error {
+		msg := "altered registered router"
+
 		if application.router == nil {
-			application.router = router.New(application.health, opts...)
+			application.router = router.New(application.health)
 
-			application.logger.Info("registered new router")
+			msg = "registered new router"
+		}
 
-			return
+		err := application.router.Add(opts...)
+		if err != nil {
+			return newOptionError("router", "", err)
 		}
 
-		application.router.Add(opts...)
+		application.logger.Info(msg)
 
-		application.logger.Info("altered registered router")
+		return nil
 	}
 }
 
 func WithConfig(opts ...config.Option) Option {
-	return func(application *Application) {
-		cfg, err := config.New(opts...)
+	return func(application *Application) error {
+		cfg, err := config.New(append([]config.Option{config.WithLogger(application.logger)}, opts...)...)
 		if err != nil {
-			application.logger.Error("failed to register new config", zap.Error(err))
-
-			return
+			return newOptionError("config", "", err)
 		}
 
 		if cfg.File() != "" {
-			application.logger.Info(fmt.Sprintf("using config file %s", viper.ConfigFileUsed()))
+			application.logger.Info(fmt.Sprintf("using config file %s", cfg.FileUsed()))
 		}
 
 		application.config = cfg
 
 		application.logger.Info("registered new config")
+
+		return nil
 	}
 }
 
 func WithServer(opts ...server.Option) Option {
-	return func(application *Application) {
+	return func(application *Application) error {
+		if application.router == nil {
+			return newOptionError("server", "", ErrMissingRouter)
+		}
+
+		opts = append([]server.Option{server.WithAdminHandler(application.router.AdminMux())}, opts...)
+
 		application.server = server.New(application.router.Mux(), opts...)
 
 		application.logger.Info("registered new server")
+
+		return nil
 	}
 }
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var labels = []string{"name", "operation"}
+
+var (
+	operationCount *prometheus.CounterVec
+	errorCount     *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	pools          *poolCollector
+)
+
+func init() {
+	operationCount = withRate()
+	errorCount = withError()
+	duration = withDuration()
+	pools = withPools()
+}
+
+func withRate() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "postgres_operation_total",
+		Help: "The number of operations",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withError() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "postgres_error_total",
+		Help: "The number of those operations that have failed",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withDuration() *prometheus.HistogramVec {
+	d := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "postgres_duration_seconds",
+		Help: "The amount of time those operations take",
+	}, labels)
+
+	prometheus.MustRegister(d)
+
+	return d
+}
+
+func withPools() *poolCollector {
+	p := &poolCollector{
+		dbs: make(map[string]*sql.DB),
+		open: prometheus.NewDesc("postgres_open_connections",
+			"The number of established connections, both in use and idle", []string{"name"}, nil),
+		inUse: prometheus.NewDesc("postgres_in_use_connections",
+			"The number of connections currently in use", []string{"name"}, nil),
+		idle: prometheus.NewDesc("postgres_idle_connections",
+			"The number of idle connections", []string{"name"}, nil),
+		waitCount: prometheus.NewDesc("postgres_wait_count_total",
+			"The number of connections waited for", []string{"name"}, nil),
+		waitDuration: prometheus.NewDesc("postgres_wait_duration_seconds_total",
+			"The amount of time spent waiting for new connections", []string{"name"}, nil),
+	}
+
+	prometheus.MustRegister(p)
+
+	return p
+}
+
+// poolCollector exports the connection pool stats of every Postgres dependency, labelled by its name.
+type poolCollector struct {
+	mu           sync.Mutex
+	dbs          map[string]*sql.DB
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+func (p *poolCollector) add(name string, db *sql.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dbs[name] = db
+}
+
+// remove stops exporting the stats of the given pool, unless it has since been replaced by another of the same name.
+func (p *poolCollector) remove(name string, db *sql.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dbs[name] == db {
+		delete(p.dbs, name)
+	}
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.open
+	ch <- p.inUse
+	ch <- p.idle
+	ch <- p.waitCount
+	ch <- p.waitDuration
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, db := range p.dbs {
+		stats := db.Stats()
+
+		ch <- prometheus.MustNewConstMetric(p.open, prometheus.GaugeValue, float64(stats.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(p.inUse, prometheus.GaugeValue, float64(stats.InUse), name)
+		ch <- prometheus.MustNewConstMetric(p.idle, prometheus.GaugeValue, float64(stats.Idle), name)
+		ch <- prometheus.MustNewConstMetric(p.waitCount, prometheus.CounterValue, float64(stats.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(p.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), name)
+	}
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import "time"
+
+// WithName sets the name the metrics of the dependency are labelled by. cgs.WithPostgres sets it to the name the
+// dependency is registered under.
+func WithName(name string) Option {
+	return func(postgres *Postgres) {
+		postgres.name = name
+	}
+}
+
+func WithMaxLifetime(duration time.Duration) Option {
+	return func(postgres *Postgres) {
+		postgres.maxLifetime = duration
+	}
+}
+
+func WithMaxOpenConnections(connections int) Option {
+	return func(postgres *Postgres) {
+		postgres.maxOpenCons = connections
+	}
+}
+
+func WithMaxIdleConnections(connections int) Option {
+	return func(postgres *Postgres) {
+		postgres.maxIdleCons = connections
+	}
+}
@@ -113,3 +113,8 @@ func (k *KafkaPublisher) Ping(ctx context.Context) error {
 
 	return nil
 }
+
+// Close flushes any pending messages before closing the underlying writer.
+func (k *KafkaPublisher) Close() error {
+	return k.publisher.Close(k.topic)
+}
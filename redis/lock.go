@@ -0,0 +1,228 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultLockRetryInterval = time.Millisecond * 100
+	minLockTTL               = time.Millisecond * 3
+
+	lockTokenLength  = 16
+	lockExtendFactor = 3
+
+	operationLock   = "lock"
+	operationUnlock = "unlock"
+	operationExtend = "extend"
+)
+
+// The results of the lock attempts, releases and losses counted by redis_lock_total.
+const (
+	lockAcquired  = "acquired"
+	lockContended = "contended"
+	lockReleased  = "released"
+	lockLost      = "lost"
+)
+
+var (
+	ErrLockNotAcquired = errors.New("lock is held elsewhere")
+	ErrLockNotHeld     = errors.New("lock is no longer held")
+	ErrInvalidLockTTL  = errors.New("lock ttl must be at least 3ms")
+)
+
+// unlockScript deletes the lock only should it still hold the token of its holder, so a lock which expired and was
+// taken by another is never released by mistake.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// extendScript resets the expiry of the lock only should it still hold the token of its holder.
+var extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Lock is a lock held on a single key. Whilst held, its lease is extended every third of its ttl, so it is only lost
+// should the holder be unable to reach Redis for the whole ttl, or the key be taken from it.
+type Lock struct {
+	r      *Redis
+	key    string
+	token  string
+	ttl    time.Duration
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// TryLock takes the lock on the given key, which expires after ttl unless extended, failing with ErrLockNotAcquired
+// should it be held elsewhere.
+func (r *Redis) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	if ttl < minLockTTL {
+		return nil, ErrInvalidLockTTL
+	}
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	ok, err := r.provider.SetNX(ctx, key, token, ttl).Result()
+	r.observe(operationLock, start, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		lockCount.WithLabelValues(r.name, lockContended).Inc()
+
+		return nil, ErrLockNotAcquired
+	}
+
+	lockCount.WithLabelValues(r.name, lockAcquired).Inc()
+
+	extendCtx, cancel := context.WithCancel(context.Background())
+
+	l := &Lock{
+		r:      r,
+		key:    key,
+		token:  token,
+		ttl:    ttl,
+		lost:   make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go l.extend(extendCtx)
+
+	return l, nil
+}
+
+// Lock takes the lock on the given key as TryLock does, retrying at the interval given by WithLockRetryInterval
+// whilst it is held elsewhere until the context is done.
+func (r *Redis) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	ticker := time.NewTicker(r.lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		l, err := r.TryLock(ctx, key, ttl)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return l, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Key returns the key locked.
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Lost is closed should the lock be lost whilst held, at which point the work it guards should stop.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops extending the lock and releases it, failing with ErrLockNotHeld should it have been lost.
+func (l *Lock) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+
+	start := time.Now()
+
+	released, err := unlockScript.Run(ctx, l.r.provider, []string{l.key}, l.token).Int()
+	l.r.observe(operationUnlock, start, err)
+
+	if err != nil {
+		return err
+	}
+
+	if released == 0 {
+		l.markLost()
+
+		return ErrLockNotHeld
+	}
+
+	lockCount.WithLabelValues(l.r.name, lockReleased).Inc()
+
+	return nil
+}
+
+// extend extends the lease of the lock until released or lost. Failing to reach Redis is retried until the lease
+// would have expired.
+func (l *Lock) extend(ctx context.Context) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / lockExtendFactor)
+	defer ticker.Stop()
+
+	expires := time.Now().Add(l.ttl)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+
+		extended, err := extendScript.Run(ctx, l.r.provider, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+		if ctx.Err() != nil {
+			return
+		}
+
+		l.r.observe(operationExtend, start, err)
+
+		if err == nil && extended == 1 {
+			expires = start.Add(l.ttl)
+
+			continue
+		}
+
+		if err == nil || time.Now().After(expires) {
+			l.markLost()
+
+			return
+		}
+	}
+}
+
+func (l *Lock) markLost() {
+	l.once.Do(func() {
+		lockCount.WithLabelValues(l.r.name, lockLost).Inc()
+
+		close(l.lost)
+	})
+}
+
+func newLockToken() (string, error) {
+	b := make([]byte, lockTokenLength)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
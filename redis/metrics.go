@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Commands beyond those instrumented by promred are recorded here, labelled by the name of the dependency and the
+// operation, such as lock or unlock, rather than by the raw command.
+var labels = []string{"name", "operation"}
+
+var (
+	operationCount *prometheus.CounterVec
+	errorCount     *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	lockCount      *prometheus.CounterVec
+	rateLimitCount *prometheus.CounterVec
+)
+
+func init() {
+	operationCount = withRate()
+	errorCount = withError()
+	duration = withDuration()
+	lockCount = withLocks()
+	rateLimitCount = withRateLimits()
+}
+
+func withRate() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_command_total",
+		Help: "The number of commands",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withError() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_command_error_total",
+		Help: "The number of those commands that have failed",
+	}, labels)
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withDuration() *prometheus.HistogramVec {
+	d := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "redis_command_duration_seconds",
+		Help: "The amount of time those commands take",
+	}, labels)
+
+	prometheus.MustRegister(d)
+
+	return d
+}
+
+func withLocks() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_lock_total",
+		Help: "The number of lock attempts, releases and losses, by their result",
+	}, []string{"name", "result"})
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+func withRateLimits() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_rate_limit_total",
+		Help: "The number of requests checked against each rate limiter, by whether they were allowed",
+	}, []string{"name", "limiter", "result"})
+
+	prometheus.MustRegister(r)
+
+	return r
+}
+
+// observe records the duration of an operation, and whether it failed.
+func (r *Redis) observe(operation string, start time.Time, err error) {
+	operationCount.WithLabelValues(r.name, operation).Inc()
+	duration.WithLabelValues(r.name, operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		errorCount.WithLabelValues(r.name, operation).Inc()
+	}
+}
@@ -1,5 +1,18 @@
 package redis
 
+import (
+	"crypto/tls"
+	"time"
+)
+
+// WithName sets the name the metrics of the dependency are labelled by. cgs.WithRedis sets it to the name the
+// dependency is registered under.
+func WithName(name string) Option {
+	return func(redis *Redis) {
+		redis.name = name
+	}
+}
+
 func WithMasterName(masterName string) Option {
 	return func(redis *Redis) {
 		redis.masterName = masterName
@@ -12,6 +25,71 @@ func WithPassword(password string) Option {
 	}
 }
 
+// WithUsername authenticates as the given ACL user, rather than the default user.
+func WithUsername(username string) Option {
+	return func(redis *Redis) {
+		redis.username = username
+	}
+}
+
+// WithSentinelPassword authenticates with the sentinels of a FailOver client, should they require a password of their
+// own.
+func WithSentinelPassword(password string) Option {
+	return func(redis *Redis) {
+		redis.sentinelPassword = password
+	}
+}
+
+// WithTLS connects over TLS as given by conf, which may be loaded from files via TLSConfig.Load.
+func WithTLS(conf *tls.Config) Option {
+	return func(redis *Redis) {
+		redis.tlsConfig = conf
+	}
+}
+
+// WithPoolSize sets the most connections held open per node. Defaults to 10 per CPU, or 5 per CPU given a cluster.
+func WithPoolSize(size int) Option {
+	return func(redis *Redis) {
+		redis.poolSize = size
+	}
+}
+
+// WithMinIdleConns sets how many idle connections are kept open per node, so that bursts need not wait on new ones.
+func WithMinIdleConns(conns int) Option {
+	return func(redis *Redis) {
+		redis.minIdleConns = conns
+	}
+}
+
+// WithDialTimeout sets how long a new connection may take to be established. Defaults to 5s.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(redis *Redis) {
+		redis.dialTimeout = timeout
+	}
+}
+
+// WithReadTimeout sets how long a command may wait on its reply. Defaults to 3s.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(redis *Redis) {
+		redis.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets how long a command may take to be written. Defaults to the read timeout.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(redis *Redis) {
+		redis.writeTimeout = timeout
+	}
+}
+
+// WithMaxRetries sets how many times a failed command is retried, or -1 to never retry. Defaults to 3, other than
+// given a cluster, which only retries commands redirected between nodes.
+func WithMaxRetries(retries int) Option {
+	return func(redis *Redis) {
+		redis.maxRetries = retries
+	}
+}
+
 func WithDB(db int) Option {
 	return func(redis *Redis) {
 		redis.db = db
@@ -23,3 +101,10 @@ func WithClientType(clientFunc ClientFunc) Option {
 		redis.clientFunc = clientFunc
 	}
 }
+
+// WithLockRetryInterval sets how often Lock retries whilst the lock is held elsewhere. Defaults to 100ms.
+func WithLockRetryInterval(interval time.Duration) Option {
+	return func(redis *Redis) {
+		redis.lockRetryInterval = interval
+	}
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	rateLimitPrefix = "rate-limit"
+
+	operationRateLimit = "rate_limit"
+)
+
+// The decisions of the rate limiter counted by redis_rate_limit_total.
+const (
+	rateLimitAllowed = "allowed"
+	rateLimitLimited = "limited"
+	rateLimitFailed  = "failed"
+)
+
+var ErrInvalidRateLimit = errors.New("rate limit must be positive, allowing at most one request per millisecond")
+
+// gcraScript implements the generic cell rate algorithm. The key holds the theoretical arrival time, in milliseconds,
+// of the next request. A request is allowed should that time be no further ahead of now than the period, in which case
+// it is pushed back by the interval between requests. Redis' own clock is used, so that every replica agrees.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+
+local interval = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+local next = tat + interval
+local wait = next - period - now
+if wait > 0 then
+	return {0, wait}
+end
+
+redis.call("SET", KEYS[1], next, "PX", next - now)
+
+return {1, 0}
+`)
+
+// RateLimiter allows up to limit requests per key within any period, spread evenly, such that a key which has been
+// idle may burst up to the whole limit at once. See Redis.RateLimiter.
+type RateLimiter struct {
+	r        *Redis
+	name     string
+	interval time.Duration
+	period   time.Duration
+}
+
+// RateLimiter returns a limiter allowing up to limit requests per key within any period. Its keys are namespaced by
+// the given name, which labels its decisions within redis_rate_limit_total.
+func (r *Redis) RateLimiter(name string, limit int, period time.Duration) (*RateLimiter, error) {
+	if limit <= 0 || period/time.Duration(limit) < time.Millisecond {
+		return nil, ErrInvalidRateLimit
+	}
+
+	return &RateLimiter{
+		r:        r,
+		name:     name,
+		interval: period / time.Duration(limit),
+		period:   period,
+	}, nil
+}
+
+// Allow reports whether the request identified by key may proceed, and should it not, how long until it may.
+func (l *RateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	start := time.Now()
+
+	res, err := gcraScript.Run(ctx, l.r.provider, []string{l.key(key)},
+		l.interval.Milliseconds(), l.period.Milliseconds()).Int64Slice()
+	l.r.observe(operationRateLimit, start, err)
+
+	if err != nil {
+		rateLimitCount.WithLabelValues(l.r.name, l.name, rateLimitFailed).Inc()
+
+		return false, 0, err
+	}
+
+	if res[0] == 0 {
+		rateLimitCount.WithLabelValues(l.r.name, l.name, rateLimitLimited).Inc()
+
+		return false, time.Duration(res[1]) * time.Millisecond, nil
+	}
+
+	rateLimitCount.WithLabelValues(l.r.name, l.name, rateLimitAllowed).Inc()
+
+	return true, 0, nil
+}
+
+func (l *RateLimiter) key(key string) string {
+	return fmt.Sprintf("%s:%s:%s", rateLimitPrefix, l.name, key)
+}
@@ -1,60 +1,119 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
+	"time"
+
 	"github.com/go-redis/redis/v8"
 	instr "github.com/jamieaitken/promred/redis"
 )
 
 const (
+	defaultName       = "default"
 	defaultMasterName = "mymaster"
 	defaultPassword   = ""
 	defaultDB         = 0
 )
 
 type Redis struct {
+	name       string
 	masterName string
 	addrs      []string
+	username   string
 	password   string
 	db         int
 	clientFunc ClientFunc
+	provider   redis.UniversalClient
 	client     instr.Redis
+
+	sentinelPassword string
+	tlsConfig        *tls.Config
+	poolSize         int
+	minIdleConns     int
+	dialTimeout      time.Duration
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	maxRetries       int
+
+	lockRetryInterval time.Duration
 }
 
 type Option func(*Redis)
 
-type ClientFunc func(r *Redis) *redis.Client
+// ClientFunc creates the client wrapped by the dependency, which may be a single node, sentinel or cluster client.
+type ClientFunc func(r *Redis) redis.UniversalClient
 
-func FailOver(r *Redis) *redis.Client {
+// FailOver connects to the master named by WithMasterName, discovered via the given addrs, each a sentinel. Sentinels
+// are authenticated by WithSentinelPassword, rather than WithPassword.
+func FailOver(r *Redis) redis.UniversalClient {
 	return redis.NewFailoverClient(&redis.FailoverOptions{
-		MasterName:    r.masterName,
-		SentinelAddrs: r.addrs,
-		Password:      r.password,
-		DB:            r.db,
+		MasterName:       r.masterName,
+		SentinelAddrs:    r.addrs,
+		SentinelPassword: r.sentinelPassword,
+		Username:         r.username,
+		Password:         r.password,
+		DB:               r.db,
+		MaxRetries:       r.maxRetries,
+		DialTimeout:      r.dialTimeout,
+		ReadTimeout:      r.readTimeout,
+		WriteTimeout:     r.writeTimeout,
+		PoolSize:         r.poolSize,
+		MinIdleConns:     r.minIdleConns,
+		TLSConfig:        r.tlsConfig,
 	})
 }
 
-func NonFailOver(r *Redis) *redis.Client {
+func NonFailOver(r *Redis) redis.UniversalClient {
 	return redis.NewClient(&redis.Options{
-		Addr:     r.addrs[0],
-		Password: r.password,
-		DB:       r.db,
+		Addr:         r.addrs[0],
+		Username:     r.username,
+		Password:     r.password,
+		DB:           r.db,
+		MaxRetries:   r.maxRetries,
+		DialTimeout:  r.dialTimeout,
+		ReadTimeout:  r.readTimeout,
+		WriteTimeout: r.writeTimeout,
+		PoolSize:     r.poolSize,
+		MinIdleConns: r.minIdleConns,
+		TLSConfig:    r.tlsConfig,
+	})
+}
+
+// Cluster connects to a Redis Cluster via the given addrs, each a seed node from which the rest of the cluster is
+// discovered. Clusters only have a single database, so DB is ignored.
+func Cluster(r *Redis) redis.UniversalClient {
+	return redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:        r.addrs,
+		Username:     r.username,
+		Password:     r.password,
+		MaxRetries:   r.maxRetries,
+		DialTimeout:  r.dialTimeout,
+		ReadTimeout:  r.readTimeout,
+		WriteTimeout: r.writeTimeout,
+		PoolSize:     r.poolSize,
+		MinIdleConns: r.minIdleConns,
+		TLSConfig:    r.tlsConfig,
 	})
 }
 
 func New(addrs []string, opts ...Option) *Redis {
 	r := &Redis{
+		name:       defaultName,
 		addrs:      addrs,
 		masterName: defaultMasterName,
 		password:   defaultPassword,
 		db:         defaultDB,
 		clientFunc: FailOver,
+
+		lockRetryInterval: defaultLockRetryInterval,
 	}
 
 	r.add(opts...)
 
-	client := r.clientFunc(r)
+	r.provider = r.clientFunc(r)
 
-	r.client = instr.New(client)
+	r.client = instr.New(r.provider)
 
 	return r
 }
@@ -69,6 +128,34 @@ func (r *Redis) Client() instr.Redis {
 	return r.client
 }
 
+// Provider returns the client wrapped by the dependency, for commands which are not instrumented by Client.
+func (r *Redis) Provider() redis.UniversalClient {
+	return r.provider
+}
+
+// Ping pings Redis via the instrumented client. Given a cluster, every master is pinged too, as each holds a share of
+// the keyspace.
+func (r *Redis) Ping(ctx context.Context) error {
+	err := r.client.Ping(ctx, "application").Err()
+	if err != nil {
+		return err
+	}
+
+	cluster, ok := r.provider.(*redis.ClusterClient)
+	if !ok {
+		return nil
+	}
+
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+		return master.Ping(ctx).Err()
+	})
+}
+
+// Name returns the name the metrics of the dependency are labelled by.
+func (r *Redis) Name() string {
+	return r.name
+}
+
 func (r *Redis) Addrs() []string {
 	return r.addrs
 }
@@ -77,10 +164,51 @@ func (r *Redis) MasterName() string {
 	return r.masterName
 }
 
+func (r *Redis) Username() string {
+	return r.username
+}
+
 func (r *Redis) Password() string {
 	return r.password
 }
 
+func (r *Redis) SentinelPassword() string {
+	return r.sentinelPassword
+}
+
+// TLSConfig returns the TLS config given via WithTLS, or nil should the connection be in plaintext.
+func (r *Redis) TLSConfig() *tls.Config {
+	return r.tlsConfig
+}
+
+func (r *Redis) PoolSize() int {
+	return r.poolSize
+}
+
+func (r *Redis) MinIdleConns() int {
+	return r.minIdleConns
+}
+
+func (r *Redis) DialTimeout() time.Duration {
+	return r.dialTimeout
+}
+
+func (r *Redis) ReadTimeout() time.Duration {
+	return r.readTimeout
+}
+
+func (r *Redis) WriteTimeout() time.Duration {
+	return r.writeTimeout
+}
+
+func (r *Redis) MaxRetries() int {
+	return r.maxRetries
+}
+
 func (r *Redis) DB() int {
 	return r.db
 }
+
+func (r *Redis) Close() error {
+	return r.provider.Close()
+}
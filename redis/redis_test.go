@@ -1,10 +1,18 @@
 package redis_test
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
 	"testing"
+	"time"
 
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jamieaitken/cgs/redis"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestNew(t *testing.T) {
@@ -76,3 +84,289 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_ClientType(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenAddrs       []string
+		givenClientFunc  redis.ClientFunc
+		expectedProvider interface{}
+	}{
+		{
+			name:             "given failover, expect a sentinel backed client",
+			givenAddrs:       []string{"localhost:26379"},
+			givenClientFunc:  redis.FailOver,
+			expectedProvider: &goredis.Client{},
+		},
+		{
+			name:             "given non failover, expect a single node client",
+			givenAddrs:       []string{"localhost:6379"},
+			givenClientFunc:  redis.NonFailOver,
+			expectedProvider: &goredis.Client{},
+		},
+		{
+			name:             "given cluster, expect a cluster client",
+			givenAddrs:       []string{"localhost:7000", "localhost:7001", "localhost:7002"},
+			givenClientFunc:  redis.Cluster,
+			expectedProvider: &goredis.ClusterClient{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := redis.New(test.givenAddrs, redis.WithClientType(test.givenClientFunc))
+
+			defer actual.Close()
+
+			actualType, expectedType := fmt.Sprintf("%T", actual.Provider()), fmt.Sprintf("%T", test.expectedProvider)
+			if !cmp.Equal(actualType, expectedType) {
+				t.Fatalf(cmp.Diff(actualType, expectedType))
+			}
+		})
+	}
+}
+
+func TestNew_ConnectionOptions(t *testing.T) {
+	type connection struct {
+		Username     string
+		Password     string
+		PoolSize     int
+		MinIdleConns int
+		DialTimeout  time.Duration
+		ReadTimeout  time.Duration
+		WriteTimeout time.Duration
+		MaxRetries   int
+		TLS          bool
+	}
+
+	tests := []struct {
+		name               string
+		givenAddrs         []string
+		givenClientFunc    redis.ClientFunc
+		expectedConnection connection
+	}{
+		{
+			name:            "given failover, expect the options to be applied to the master's connections",
+			givenAddrs:      []string{"localhost:26379"},
+			givenClientFunc: redis.FailOver,
+			expectedConnection: connection{
+				Username: "cache", Password: "hunter", PoolSize: 20, MinIdleConns: 5, DialTimeout: time.Second,
+				ReadTimeout: time.Second * 2, WriteTimeout: time.Second * 3, MaxRetries: 2, TLS: true,
+			},
+		},
+		{
+			name:            "given non failover, expect the options to be applied to the node's connections",
+			givenAddrs:      []string{"localhost:6379"},
+			givenClientFunc: redis.NonFailOver,
+			expectedConnection: connection{
+				Username: "cache", Password: "hunter", PoolSize: 20, MinIdleConns: 5, DialTimeout: time.Second,
+				ReadTimeout: time.Second * 2, WriteTimeout: time.Second * 3, MaxRetries: 2, TLS: true,
+			},
+		},
+		{
+			name:            "given cluster, expect the options to be applied to every node's connections",
+			givenAddrs:      []string{"localhost:7000", "localhost:7001", "localhost:7002"},
+			givenClientFunc: redis.Cluster,
+			expectedConnection: connection{
+				Username: "cache", Password: "hunter", PoolSize: 20, MinIdleConns: 5, DialTimeout: time.Second,
+				ReadTimeout: time.Second * 2, WriteTimeout: time.Second * 3, MaxRetries: 2, TLS: true,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := redis.New(test.givenAddrs,
+				redis.WithClientType(test.givenClientFunc),
+				redis.WithUsername("cache"),
+				redis.WithPassword("hunter"),
+				redis.WithSentinelPassword("sentinel"),
+				redis.WithTLS(&tls.Config{MinVersion: tls.VersionTLS12}),
+				redis.WithPoolSize(20),
+				redis.WithMinIdleConns(5),
+				redis.WithDialTimeout(time.Second),
+				redis.WithReadTimeout(time.Second*2),
+				redis.WithWriteTimeout(time.Second*3),
+				redis.WithMaxRetries(2),
+			)
+
+			defer r.Close()
+
+			var actual connection
+
+			switch provider := r.Provider().(type) {
+			case *goredis.Client:
+				o := provider.Options()
+				actual = connection{
+					Username: o.Username, Password: o.Password, PoolSize: o.PoolSize, MinIdleConns: o.MinIdleConns,
+					DialTimeout: o.DialTimeout, ReadTimeout: o.ReadTimeout, WriteTimeout: o.WriteTimeout,
+					MaxRetries: o.MaxRetries, TLS: o.TLSConfig != nil,
+				}
+			case *goredis.ClusterClient:
+				o := provider.Options()
+				actual = connection{
+					Username: o.Username, Password: o.Password, PoolSize: o.PoolSize, MinIdleConns: o.MinIdleConns,
+					DialTimeout: o.DialTimeout, ReadTimeout: o.ReadTimeout, WriteTimeout: o.WriteTimeout,
+					MaxRetries: o.MaxRetries, TLS: o.TLSConfig != nil,
+				}
+			}
+
+			if !cmp.Equal(actual, test.expectedConnection) {
+				t.Fatalf(cmp.Diff(actual, test.expectedConnection))
+			}
+		})
+	}
+}
+
+func TestTLSConfig_Load_Fail(t *testing.T) {
+	empty, err := ioutil.TempFile(t.TempDir(), "ca-*.crt")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	defer empty.Close()
+
+	tests := []struct {
+		name          string
+		givenConfig   redis.TLSConfig
+		expectedError error
+	}{
+		{
+			name:          "given a missing CA file, expect error to be raised",
+			givenConfig:   redis.TLSConfig{CAFile: "testdata/missing-ca.crt"},
+			expectedError: redis.ErrInvalidTLSConfig,
+		},
+		{
+			name:          "given a CA file holding no certificates, expect error to be raised",
+			givenConfig:   redis.TLSConfig{CAFile: empty.Name()},
+			expectedError: redis.ErrInvalidTLSConfig,
+		},
+		{
+			name:          "given a missing client certificate, expect error to be raised",
+			givenConfig:   redis.TLSConfig{CertFile: "testdata/missing.crt", KeyFile: "testdata/missing.key"},
+			expectedError: redis.ErrInvalidTLSConfig,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.givenConfig.Load()
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatalf(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestRedis_TryLock_Fail(t *testing.T) {
+	tests := []struct {
+		name               string
+		givenName          string
+		givenTTL           time.Duration
+		expectedError      error
+		expectedErrorCount float64
+	}{
+		{
+			name:          "given a ttl too short to be extended, expect error",
+			givenName:     "lock-invalid-ttl",
+			givenTTL:      time.Millisecond,
+			expectedError: redis.ErrInvalidLockTTL,
+		},
+		{
+			name:               "given an unreachable redis, expect the failed lock to be counted under the dependency's name",
+			givenName:          "lock-unreachable",
+			givenTTL:           time.Second,
+			expectedErrorCount: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := redis.New([]string{"127.0.0.1:1"},
+				redis.WithName(test.givenName),
+				redis.WithClientType(redis.NonFailOver),
+			)
+
+			defer r.Close()
+
+			labels := map[string]string{"name": test.givenName, "operation": "lock"}
+
+			// The counter is shared by every run of the test within the process, so only its increase is asserted.
+			before := gather(t, "redis_command_error_total", labels)
+
+			_, err := r.TryLock(context.Background(), "lock", test.givenTTL)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			if test.expectedError != nil && !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatalf(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			actual := gather(t, "redis_command_error_total", labels) - before
+			if !cmp.Equal(actual, test.expectedErrorCount) {
+				t.Fatalf(cmp.Diff(actual, test.expectedErrorCount))
+			}
+		})
+	}
+}
+
+// gather returns the value of the counter of the given name with the given labels, which is 0 should it not exist.
+func gather(t *testing.T, name string, labels map[string]string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			matched := 0
+
+			for _, label := range m.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					matched++
+				}
+			}
+
+			if matched == len(labels) {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestRedis_RateLimiter_Fail(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenLimit    int
+		givenPeriod   time.Duration
+		expectedError error
+	}{
+		{
+			name:          "given no limit, expect error",
+			givenLimit:    0,
+			givenPeriod:   time.Second,
+			expectedError: redis.ErrInvalidRateLimit,
+		},
+		{
+			name:          "given more than one request per millisecond, expect error",
+			givenLimit:    2000,
+			givenPeriod:   time.Second,
+			expectedError: redis.ErrInvalidRateLimit,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := redis.New([]string{"127.0.0.1:1"}, redis.WithClientType(redis.NonFailOver))
+
+			defer r.Close()
+
+			_, err := r.RateLimiter("test", test.givenLimit, test.givenPeriod)
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatalf(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
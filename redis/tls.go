@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var ErrInvalidTLSConfig = errors.New("failed to load redis tls config")
+
+// TLSConfig verifies the server against the given CA bundle, rather than the system's roots, and presents the given
+// client certificate, should both CertFile and KeyFile be set.
+type TLSConfig struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// ServerName is verified against the server's certificate, defaulting to the host of each addr.
+	ServerName string
+}
+
+// Load reads the files given by the config, returning a TLS config which can be given to WithTLS.
+func (t TLSConfig) Load() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if t.CAFile != "" {
+		ca, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", err, ErrInvalidTLSConfig)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("%s holds no certificates: %w", t.CAFile, ErrInvalidTLSConfig)
+		}
+
+		tlsCfg.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", err, ErrInvalidTLSConfig)
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
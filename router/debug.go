@@ -0,0 +1,88 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/jamieaitken/cgs/config"
+)
+
+const configEndpointPath = "/debug/config"
+
+var ErrMissingConfig = errors.New("config endpoint requires a config")
+
+// EndpointOption configures where a debug endpoint is served.
+type EndpointOption func(*endpoint)
+
+type endpoint struct {
+	admin bool
+}
+
+// OnAdmin serves the endpoint on the admin listener only, rather than alongside every other route. See
+// server.WithAdminAddr.
+func OnAdmin() EndpointOption {
+	return func(e *endpoint) {
+		e.admin = true
+	}
+}
+
+// ConfigSetting is the resolved value of a single key, alongside the source it was read from.
+type ConfigSetting struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// WithConfigEndpoint serves every resolved key of the given config, its value and source as JSON from
+// /debug/config. Values are redacted as per config.Config.Redacted, so secrets, passwords and DSN credentials are
+// never served. Without a config, the endpoint is not served and Add fails with ErrMissingConfig.
+func WithConfigEndpoint(cfg *config.Config, opts ...EndpointOption) Option {
+	return func(router *Router) {
+		if cfg == nil {
+			router.fail(ErrMissingConfig)
+
+			return
+		}
+
+		e := &endpoint{}
+
+		for _, opt := range opts {
+			opt(e)
+		}
+
+		mux := router.mux
+		if e.admin {
+			mux = router.adminMux
+		}
+
+		mux.Handle(configEndpointPath, handlers.MethodHandler{
+			http.MethodGet: http.HandlerFunc(router.instrumentation.HandleFor(configHandler(cfg))),
+		})
+	}
+}
+
+func configHandler(cfg *config.Config) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		sources := cfg.Sources()
+
+		settings := make(map[string]ConfigSetting)
+
+		for key, value := range cfg.Redacted() {
+			settings[key] = ConfigSetting{
+				Value:  value,
+				Source: sources[key].String(),
+			}
+		}
+
+		b, err := json.Marshal(settings)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(b)
+	}
+}
@@ -0,0 +1,28 @@
+package router
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// The decisions counted by router_rate_limit_total. Requests are let through, rather than denied, whenever the limiter
+// fails to decide.
+const (
+	decisionAllowed = "allowed"
+	decisionDenied  = "denied"
+	decisionError   = "error"
+)
+
+var rateLimitCount *prometheus.CounterVec
+
+func init() {
+	rateLimitCount = withRateLimits()
+}
+
+func withRateLimits() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "router_rate_limit_total",
+		Help: "The number of requests checked by WithRateLimit, by the limiter's decision",
+	}, []string{"decision"})
+
+	prometheus.MustRegister(r)
+
+	return r
+}
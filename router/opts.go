@@ -6,9 +6,11 @@ import (
 	"net/http"
 )
 
-func WithRoute(route Route) Option {
+// WithRoute serves the given route, wrapping each of its handlers with every given RouteOption in order, the first
+// being outermost.
+func WithRoute(route Route, opts ...RouteOption) Option {
 	return func(router *Router) {
-		router.Mux().Handle(route.Path, buildHandler(router, route))
+		router.Mux().Handle(route.Path, buildHandler(router, route, opts))
 	}
 }
 
@@ -18,10 +20,14 @@ func WithTracer(opts ...requestid.Option) Option {
 	}
 }
 
-func buildHandler(router *Router, route Route) http.Handler {
+func buildHandler(router *Router, route Route, opts []RouteOption) http.Handler {
 	h := handlers.MethodHandler{}
 
 	for method, handler := range route.HandlerFuncs {
+		for i := len(opts) - 1; i >= 0; i-- {
+			handler = opts[i](handler)
+		}
+
 		h[method] = router.tracer.Trace(router.instrumentation.HandleFor(handler))
 	}
 
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultErrorLogInterval = time.Minute
+
+// Limiter decides whether the request identified by key may proceed and, should it not, how long until it may.
+// redis.RateLimiter is a Limiter.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// KeyFunc returns the key a request is rate limited by, such as the client's IP or API key. Requests given an empty
+// key share a single limit.
+type KeyFunc func(request *http.Request) string
+
+// RouteOption wraps every handler of a single route.
+type RouteOption func(http.HandlerFunc) http.HandlerFunc
+
+// RateLimitOption configures how WithRateLimit reports a failing limiter.
+type RateLimitOption func(*rateLimit)
+
+type rateLimit struct {
+	logger           *zap.Logger
+	errorLogInterval time.Duration
+	lastLogged       int64
+	failures         int64
+}
+
+// LogErrors logs limiter errors to the given logger, at most once per interval alongside how many requests were let
+// through since, so that a limiter which is down does not flood the logs. By default, they are only counted.
+func LogErrors(logger *zap.Logger, interval time.Duration) RateLimitOption {
+	return func(r *rateLimit) {
+		r.logger = logger
+		r.errorLogInterval = interval
+	}
+}
+
+// WithRateLimit responds 429 Too Many Requests, with a Retry-After header in seconds, to every request the limiter
+// denies, keyed by the given func. Should the limiter fail, requests are allowed through rather than the route being
+// taken down with it. Every decision, including the limiter failing to make one, is counted by
+// router_rate_limit_total.
+func WithRateLimit(limiter Limiter, keyFunc KeyFunc, opts ...RateLimitOption) RouteOption {
+	r := &rateLimit{
+		logger:           zap.NewNop(),
+		errorLogInterval: defaultErrorLogInterval,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(writer http.ResponseWriter, request *http.Request) {
+			allowed, retryAfter, err := limiter.Allow(request.Context(), keyFunc(request))
+			if err != nil {
+				rateLimitCount.WithLabelValues(decisionError).Inc()
+				r.logError(err)
+
+				next(writer, request)
+
+				return
+			}
+
+			if allowed {
+				rateLimitCount.WithLabelValues(decisionAllowed).Inc()
+
+				next(writer, request)
+
+				return
+			}
+
+			rateLimitCount.WithLabelValues(decisionDenied).Inc()
+
+			writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writer.WriteHeader(http.StatusTooManyRequests)
+		}
+	}
+}
+
+// logError logs the given error should none have been logged within the interval, along with the number of failures
+// since the last was logged.
+func (r *rateLimit) logError(err error) {
+	atomic.AddInt64(&r.failures, 1)
+
+	now := time.Now().UnixNano()
+
+	last := atomic.LoadInt64(&r.lastLogged)
+	if last != 0 && now-last < r.errorLogInterval.Nanoseconds() {
+		return
+	}
+
+	if !atomic.CompareAndSwapInt64(&r.lastLogged, last, now) {
+		return
+	}
+
+	r.logger.Error("rate limiter failed, letting requests through", zap.Error(err),
+		zap.Int64("failures", atomic.SwapInt64(&r.failures, 0)))
+}
+
+// ByClientIP keys requests by the IP they were received from. Behind a proxy or load balancer this is the proxy's IP,
+// so ByHeader should be used with the header it sets instead.
+func ByClientIP() KeyFunc {
+	return func(request *http.Request) string {
+		host, _, err := net.SplitHostPort(request.RemoteAddr)
+		if err != nil {
+			return request.RemoteAddr
+		}
+
+		return host
+	}
+}
+
+// ByHeader keys requests by the value of the given header, such as X-API-Key. Requests without the header are keyed by
+// their client IP instead, as per ByClientIP, rather than every one of them sharing a single limit.
+func ByHeader(header string) KeyFunc {
+	byClientIP := ByClientIP()
+
+	return func(request *http.Request) string {
+		key := request.Header.Get(header)
+		if key == "" {
+			return byClientIP(request)
+		}
+
+		return key
+	}
+}
@@ -12,8 +12,10 @@ import (
 
 type Router struct {
 	mux             *http.ServeMux
+	adminMux        *http.ServeMux
 	instrumentation handler.Handler
 	tracer          *requestid.Tracer
+	err             error
 }
 
 type Route struct {
@@ -23,6 +25,8 @@ type Route struct {
 
 type Option func(*Router)
 
+// New returns a router serving metrics, liveness and readiness alongside the routes given by opts. Any option which
+// fails is skipped; give it to Add instead to be told why.
 func New(health healthcheck.Handler, opts ...Option) *Router {
 	mux := http.NewServeMux()
 
@@ -42,6 +46,7 @@ func New(health healthcheck.Handler, opts ...Option) *Router {
 
 	r := &Router{
 		mux:             mux,
+		adminMux:        http.NewServeMux(),
 		instrumentation: instrHandler,
 		tracer:          tracer,
 	}
@@ -55,8 +60,29 @@ func (r *Router) Mux() *http.ServeMux {
 	return r.mux
 }
 
-func (r *Router) Add(options ...Option) {
+// AdminMux returns the mux of endpoints which are only served on the server's admin listener.
+func (r *Router) AdminMux() *http.ServeMux {
+	return r.adminMux
+}
+
+// Add applies the given options, returning the error of the first which failed, such as WithConfigEndpoint given no
+// config. Options which fail are skipped.
+func (r *Router) Add(options ...Option) error {
+	r.err = nil
+
 	for _, opt := range options {
 		opt(r)
 	}
+
+	err := r.err
+	r.err = nil
+
+	return err
+}
+
+// fail records why an option failed, keeping the first such error for Add to return.
+func (r *Router) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
 }
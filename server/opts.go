@@ -1,6 +1,7 @@
 package server
 
 import (
+	"net/http"
 	"time"
 )
 
@@ -22,8 +23,31 @@ func WithWriteTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithStopTimeout sets how long Stop waits for in-flight requests when given a context without a deadline. It defaults to
+// 5s. A context with a deadline, such as the one given to cgs.Application.Shutdown, is honoured instead.
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(server *Server) {
+		server.stopTimeout = timeout
+	}
+}
+
 func WithTLSConfig(conf *TLSConfig) Option {
 	return func(server *Server) {
 		server.tlsConfig = conf
 	}
 }
+
+// WithAdminAddr serves the admin handler on its own listener at the given address, so that admin endpoints, such as
+// /debug/config, are kept off the address served to clients. The admin listener does not use TLS.
+func WithAdminAddr(addr string) Option {
+	return func(server *Server) {
+		server.adminAddr = addr
+	}
+}
+
+// WithAdminHandler sets the handler served on the admin listener. cgs.WithServer sets it to the router's admin mux.
+func WithAdminHandler(handler http.Handler) Option {
+	return func(server *Server) {
+		server.adminHandler = handler
+	}
+}
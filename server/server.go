@@ -4,9 +4,6 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 )
 
@@ -14,15 +11,20 @@ const (
 	defaultAddr         = ":8080"
 	defaultReadTimeout  = time.Second * 30
 	defaultWriteTimeout = time.Second * 30
+	defaultStopTimeout  = time.Second * 5
 )
 
 type Server struct {
 	addr         string
 	readTimeout  time.Duration
 	writeTimeout time.Duration
+	stopTimeout  time.Duration
 	handler      http.Handler
 	tlsConfig    *TLSConfig
 	httpServer   *http.Server
+	adminAddr    string
+	adminHandler http.Handler
+	adminServer  *http.Server
 }
 
 type TLSConfig struct {
@@ -37,6 +39,7 @@ func New(handler http.Handler, opts ...Option) *Server {
 		addr:         defaultAddr,
 		readTimeout:  defaultReadTimeout,
 		writeTimeout: defaultWriteTimeout,
+		stopTimeout:  defaultStopTimeout,
 		handler:      handler,
 	}
 
@@ -49,6 +52,19 @@ func New(handler http.Handler, opts ...Option) *Server {
 		WriteTimeout: s.writeTimeout,
 	}
 
+	if s.adminAddr != "" {
+		if s.adminHandler == nil {
+			s.adminHandler = http.NotFoundHandler()
+		}
+
+		s.adminServer = &http.Server{
+			Addr:         s.adminAddr,
+			Handler:      s.adminHandler,
+			ReadTimeout:  s.readTimeout,
+			WriteTimeout: s.writeTimeout,
+		}
+	}
+
 	return s
 }
 
@@ -62,6 +78,11 @@ func (s *Server) Addr() string {
 	return s.addr
 }
 
+// AdminAddr returns the address of the admin listener, which is empty should there be none.
+func (s *Server) AdminAddr() string {
+	return s.adminAddr
+}
+
 func (s *Server) ReadTimeout() time.Duration {
 	return s.readTimeout
 }
@@ -70,44 +91,93 @@ func (s *Server) WriteTimeout() time.Duration {
 	return s.writeTimeout
 }
 
+// StopTimeout returns how long Stop waits for in-flight requests when given a context without a deadline.
+func (s *Server) StopTimeout() time.Duration {
+	return s.stopTimeout
+}
+
+// Start serves requests, alongside admin requests should there be an admin listener, until either listener fails or
+// the given context is done, at which point the server is stopped.
 func (s *Server) Start(ctx context.Context) error {
-	go func(ctx context.Context) {
-		osSignals := make(chan os.Signal, 1)
-		defer close(osSignals)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Stop(context.Background())
+		case <-done:
+		}
+	}()
 
-		signal.Notify(osSignals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGPROF)
-		<-osSignals
+	adminErr := make(chan error, 1)
 
-		s.Stop(ctx)
-	}(ctx)
+	if s.adminServer == nil {
+		adminErr <- nil
+	} else {
+		go func() {
+			err := ignoreClosed(s.adminServer.ListenAndServe())
+			if err != nil {
+				s.Stop(context.Background())
+			}
 
-	if s.tlsConfig == nil {
-		err := s.httpServer.ListenAndServe()
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			return err
-		}
+			adminErr <- err
+		}()
+	}
 
-		return nil
+	err := s.serve()
+	if err != nil && s.adminServer != nil {
+		s.adminServer.Shutdown(context.Background())
 	}
 
-	err := s.httpServer.ListenAndServeTLS(s.tlsConfig.CertFile, s.tlsConfig.KeyFile)
-	if err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return err
+	if aErr := <-adminErr; err == nil {
+		err = aErr
 	}
 
-	return nil
+	return err
 }
 
+func (s *Server) serve() error {
+	if s.tlsConfig == nil {
+		return ignoreClosed(s.httpServer.ListenAndServe())
+	}
+
+	return ignoreClosed(s.httpServer.ListenAndServeTLS(s.tlsConfig.CertFile, s.tlsConfig.KeyFile))
+}
+
+func ignoreClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Stop gracefully shuts down every listener, waiting for in-flight requests until the context's deadline or, should the
+// context have none, the stop timeout.
 func (s *Server) Stop(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, s.stopTimeout)
+		defer cancel()
+	}
 
-	c := make(chan error)
-	defer close(c)
+	c := make(chan error, 1)
 
 	go func(ctx context.Context) {
 		c <- s.httpServer.Shutdown(ctx)
 	}(ctx)
 
-	return <-c
+	var err error
+
+	if s.adminServer != nil {
+		err = s.adminServer.Shutdown(ctx)
+	}
+
+	if mainErr := <-c; mainErr != nil {
+		err = mainErr
+	}
+
+	return err
 }
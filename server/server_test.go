@@ -4,13 +4,15 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
-	"syscall"
 	"testing"
 	"time"
 
 	"github.com/jamieaitken/cgs"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jamieaitken/cgs/config"
+	"github.com/jamieaitken/cgs/router"
 	"github.com/jamieaitken/cgs/server"
 )
 
@@ -92,19 +94,18 @@ func TestServer_Start(t *testing.T) {
 				t.Fatalf("expected nil, got %v", err)
 			}
 
+			ctx, cancel := context.WithCancel(context.Background())
+
 			chn := make(chan error, 1)
 
 			go func() {
-				chn <- app.Server().Start(context.Background())
+				chn <- app.Server().Start(ctx)
 			}()
 
 			go func() {
 				time.Sleep(1 * time.Second)
 
-				err := syscall.Kill(syscall.Getpid(), syscall.SIGINT)
-				if err != nil {
-					t.Error(err)
-				}
+				cancel()
 			}()
 
 			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
@@ -120,3 +121,143 @@ func TestServer_Start(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_Start_Admin(t *testing.T) {
+	tests := []struct {
+		name                string
+		givenAddr           string
+		givenAdminAddr      string
+		expectedMainStatus  int
+		expectedAdminStatus int
+	}{
+		{
+			name:                "given an admin addr, expect admin endpoints to be served only on the admin listener",
+			givenAddr:           "127.0.0.1:38080",
+			givenAdminAddr:      "127.0.0.1:38081",
+			expectedMainStatus:  http.StatusNotFound,
+			expectedAdminStatus: http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, err := cgs.New(
+				cgs.WithConfig(config.WithEnvVars(false)),
+				cgs.WithRouter(),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			err = app.Add(
+				cgs.WithRouter(router.WithConfigEndpoint(app.Config(), router.OnAdmin())),
+				cgs.WithServer(server.WithAddr(test.givenAddr), server.WithAdminAddr(test.givenAdminAddr)),
+			)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			chn := make(chan error, 1)
+
+			go func() {
+				chn <- app.Server().Start(ctx)
+			}()
+
+			adminStatus := getStatus(t, "http://"+test.givenAdminAddr+"/debug/config")
+			if !cmp.Equal(adminStatus, test.expectedAdminStatus) {
+				t.Fatal(cmp.Diff(adminStatus, test.expectedAdminStatus))
+			}
+
+			mainStatus := getStatus(t, "http://"+test.givenAddr+"/debug/config")
+			if !cmp.Equal(mainStatus, test.expectedMainStatus) {
+				t.Fatal(cmp.Diff(mainStatus, test.expectedMainStatus))
+			}
+
+			cancel()
+
+			err = <-chn
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServer_Stop(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenAddr     string
+		givenDeadline time.Duration
+		expectedErr   error
+	}{
+		{
+			name:        "given a context without a deadline, expect the stop timeout to cut the in-flight request short",
+			givenAddr:   "127.0.0.1:38082",
+			expectedErr: context.DeadlineExceeded,
+		},
+		{
+			name:          "given a context with a deadline, expect the deadline honoured over the stop timeout",
+			givenAddr:     "127.0.0.1:38083",
+			givenDeadline: time.Second * 5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inFlight := make(chan struct{})
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/slow" {
+					return
+				}
+
+				close(inFlight)
+				time.Sleep(time.Millisecond * 500)
+			})
+
+			srv := server.New(handler, server.WithAddr(test.givenAddr), server.WithStopTimeout(time.Millisecond*50))
+
+			go srv.Start(context.Background())
+
+			getStatus(t, "http://"+test.givenAddr+"/")
+
+			go http.Get("http://" + test.givenAddr + "/slow")
+
+			<-inFlight
+
+			ctx := context.Background()
+
+			if test.givenDeadline > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, test.givenDeadline)
+				defer cancel()
+			}
+
+			err := srv.Stop(ctx)
+			if !cmp.Equal(err, test.expectedErr, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedErr, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+// getStatus returns the status of a GET of the given url, retrying whilst the listener starts.
+func getStatus(t *testing.T, url string) int {
+	deadline := time.Now().Add(time.Second * 5)
+
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+
+			return resp.StatusCode
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		time.Sleep(time.Millisecond * 50)
+	}
+}
@@ -0,0 +1,141 @@
+package cgs
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+const (
+	kindRedis      = "redis"
+	kindMySQL      = "mysql"
+	kindPostgres   = "postgres"
+	kindStream     = "stream"
+	kindPublisher  = "publisher"
+	kindSubscriber = "subscriber"
+)
+
+// dependency records a registered dependency so that it can be closed in the order it was registered.
+type dependency struct {
+	kind  string
+	name  string
+	close func() error
+}
+
+func (a *Application) register(kind, name string, closeFn func() error) {
+	a.dependencies = append(a.dependencies, dependency{
+		kind:  kind,
+		name:  name,
+		close: closeFn,
+	})
+}
+
+// Shutdown fails the readiness check, runs every OnStop hook, drains the server, stops the functions given to Run,
+// stops subscribers and stream consumers, flushes publishers and then closes Redis, MySQL and Postgres clients in the
+// reverse order to which they were registered. Every dependency which fails to close, or does not close before the
+// context's deadline, is reported in an error wrapping ErrShutdownFailed. Only the first call shuts the application
+// down; subsequent calls return its result.
+func (a *Application) Shutdown(ctx context.Context) error {
+	a.shutdownOnce.Do(func() {
+		a.shutdownErr = a.shutdown(ctx)
+	})
+
+	return a.shutdownErr
+}
+
+func (a *Application) shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&a.shuttingDown, 1)
+
+	a.logger.Info("shutting down")
+
+	var errs []error
+
+	err := a.runHooks(ctx, stageStop, a.onStop)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if a.server != nil {
+		err := a.server.Stop(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("server: %w", err))
+		}
+	}
+
+	a.mu.Lock()
+	stopRun := a.stopRun
+	a.mu.Unlock()
+
+	if stopRun != nil {
+		stopRun()
+	}
+
+	a.mu.Lock()
+	deps := make([]dependency, len(a.dependencies))
+	copy(deps, a.dependencies)
+	a.mu.Unlock()
+
+	var stores []dependency
+
+	for _, kind := range []string{kindSubscriber, kindStream, kindPublisher} {
+		for _, dep := range deps {
+			if dep.kind == kind {
+				stores = append(stores, dep)
+			}
+		}
+	}
+
+	for i := len(deps) - 1; i >= 0; i-- {
+		if deps[i].kind == kindRedis || deps[i].kind == kindMySQL || deps[i].kind == kindPostgres {
+			stores = append(stores, deps[i])
+		}
+	}
+
+	for _, dep := range stores {
+		err := closeWithContext(ctx, dep.close)
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("%s-%s failed to close", dep.name, dep.kind), zap.Error(err))
+
+			errs = append(errs, fmt.Errorf("%s-%s: %w", dep.name, dep.kind, err))
+
+			continue
+		}
+
+		a.logger.Info(fmt.Sprintf("closed %s-%s", dep.name, dep.kind))
+	}
+
+	if a.config != nil {
+		err = a.config.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: %w", err))
+		}
+	}
+
+	return newMultiError(ErrShutdownFailed, errs)
+}
+
+func (a *Application) shutdownCheck() error {
+	if atomic.LoadInt32(&a.shuttingDown) == 1 {
+		return ErrShuttingDown
+	}
+
+	return nil
+}
+
+// closeWithContext stops waiting on closeFn once the context is done.
+func closeWithContext(ctx context.Context, closeFn func() error) error {
+	c := make(chan error, 1)
+
+	go func() {
+		c <- closeFn()
+	}()
+
+	select {
+	case err := <-c:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,29 @@
+package stream
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// The results of handling a message counted by redis_stream_message_total. Messages claimed from another consumer
+// and handled are counted as claimed rather than handled, and those delivered too many times as dead lettered.
+const (
+	resultHandled      = "handled"
+	resultClaimed      = "claimed"
+	resultFailed       = "failed"
+	resultDeadLettered = "dead_lettered"
+)
+
+var messageCount *prometheus.CounterVec
+
+func init() {
+	messageCount = withMessages()
+}
+
+func withMessages() *prometheus.CounterVec {
+	r := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_stream_message_total",
+		Help: "The number of messages handled, by whether they were handled without error",
+	}, []string{"stream", "group", "result"})
+
+	prometheus.MustRegister(r)
+
+	return r
+}
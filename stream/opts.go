@@ -0,0 +1,77 @@
+package stream
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WithConsumerName sets the name of the consumer within its group, which must be unique to each replica. Defaults to
+// the host name and process ID.
+func WithConsumerName(name string) Option {
+	return func(consumer *Consumer) {
+		consumer.consumer = name
+	}
+}
+
+// WithBatchSize sets how many messages are read at once. Defaults to 10.
+func WithBatchSize(size int64) Option {
+	return func(consumer *Consumer) {
+		consumer.batchSize = size
+	}
+}
+
+// WithBlock sets how long each read waits for messages to arrive. Defaults to 2s.
+func WithBlock(block time.Duration) Option {
+	return func(consumer *Consumer) {
+		consumer.block = block
+	}
+}
+
+// WithClaimMinIdle sets how long a message must have been pending before it is claimed from the consumer it was
+// delivered to. Defaults to 1m.
+func WithClaimMinIdle(idle time.Duration) Option {
+	return func(consumer *Consumer) {
+		consumer.claimMinIdle = idle
+	}
+}
+
+// WithClaimInterval sets how often pending messages are claimed. Defaults to 30s.
+func WithClaimInterval(interval time.Duration) Option {
+	return func(consumer *Consumer) {
+		consumer.claimInterval = interval
+	}
+}
+
+// WithMaxDeliveries sets how many times a message may be delivered before, rather than being claimed once more, it is
+// moved to the dead letter stream and acknowledged. 0 claims messages however many times they have failed. Defaults
+// to 5.
+func WithMaxDeliveries(deliveries int64) Option {
+	return func(consumer *Consumer) {
+		consumer.maxDeliveries = deliveries
+	}
+}
+
+// WithDeadLetterStream sets the stream messages are moved to once delivered more times than WithMaxDeliveries
+// allows. Defaults to the stream's key suffixed with :dead-letter.
+func WithDeadLetterStream(key string) Option {
+	return func(consumer *Consumer) {
+		consumer.deadLetterStream = key
+	}
+}
+
+// WithStartID sets the ID the group starts reading from should it be created, such as 0 to read the stream from the
+// beginning. Defaults to $, reading only messages added from then on.
+func WithStartID(id string) Option {
+	return func(consumer *Consumer) {
+		consumer.startID = id
+	}
+}
+
+// WithLogger sets the logger failures to handle messages are logged to. cgs.WithRedisStream sets it to the
+// application's logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(consumer *Consumer) {
+		consumer.logger = logger
+	}
+}
@@ -0,0 +1,448 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jamieaitken/cgs/internal/detached"
+	"github.com/jamieaitken/cgs/redis"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize     = 10
+	defaultBlock         = time.Second * 2
+	defaultClaimMinIdle  = time.Minute
+	defaultClaimInterval = time.Second * 30
+	defaultStartID       = "$"
+	defaultMaxDeliveries = 5
+
+	// minReadBackoff and maxReadBackoff bound how long Run waits before reading again once a read fails, doubling
+	// with each failure in a row.
+	minReadBackoff = time.Millisecond * 100
+	maxReadBackoff = time.Second * 10
+
+	// busyGroup prefixes the error returned by XGROUP CREATE should the group already exist.
+	busyGroup = "BUSYGROUP"
+	// claimDone is the cursor returned by XAUTOCLAIM once every pending entry has been scanned.
+	claimDone = "0-0"
+)
+
+var (
+	ErrFailedToCreateGroup = errors.New("failed to create consumer group")
+	ErrGroupNotFound       = errors.New("consumer group not found")
+	ErrInvalidClaimReply   = errors.New("unexpected XAUTOCLAIM reply")
+)
+
+// Message is a single entry of the stream.
+type Message struct {
+	ID     string
+	Values map[string]interface{}
+}
+
+// Handler handles a single message. Should it return an error, the message is left pending and redelivered to a
+// consumer of the group once it has been idle for longer than the duration given by WithClaimMinIdle. The context is
+// not cancelled along with that given to Run, so that a message being handled when the consumer is stopped is
+// finished.
+type Handler func(ctx context.Context, msg Message) error
+
+// Consumer reads a stream as one consumer of a consumer group, so that each message is handled by one consumer of the
+// group. Messages left pending by a consumer which crashed are claimed via XAUTOCLAIM, requiring Redis 6.2 or later.
+type Consumer struct {
+	r                *redis.Redis
+	stream           string
+	group            string
+	consumer         string
+	handler          Handler
+	batchSize        int64
+	block            time.Duration
+	claimMinIdle     time.Duration
+	claimInterval    time.Duration
+	startID          string
+	maxDeliveries    int64
+	deadLetterStream string
+	logger           *zap.Logger
+
+	mu       sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type Option func(*Consumer)
+
+// New returns a consumer of the given stream within the given group, named after the host by default.
+func New(r *redis.Redis, stream, group string, handler Handler, opts ...Option) *Consumer {
+	hostname, _ := os.Hostname()
+
+	c := &Consumer{
+		r:                r,
+		stream:           stream,
+		group:            group,
+		consumer:         fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		handler:          handler,
+		batchSize:        defaultBatchSize,
+		block:            defaultBlock,
+		claimMinIdle:     defaultClaimMinIdle,
+		claimInterval:    defaultClaimInterval,
+		startID:          defaultStartID,
+		maxDeliveries:    defaultMaxDeliveries,
+		deadLetterStream: fmt.Sprintf("%s:dead-letter", stream),
+		logger:           zap.NewNop(),
+		stop:             make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *Consumer) Stream() string {
+	return c.stream
+}
+
+func (c *Consumer) Group() string {
+	return c.group
+}
+
+func (c *Consumer) Consumer() string {
+	return c.consumer
+}
+
+// DeadLetterStream returns the stream messages are moved to once they have been delivered too many times.
+func (c *Consumer) DeadLetterStream() string {
+	return c.deadLetterStream
+}
+
+// CreateGroup creates the consumer group, and the stream too should it not exist. A group which already exists is
+// left as it is.
+func (c *Consumer) CreateGroup(ctx context.Context) error {
+	err := c.r.Provider().XGroupCreateMkStream(ctx, c.stream, c.group, c.startID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), busyGroup) {
+		return fmt.Errorf("%v: %w", err, ErrFailedToCreateGroup)
+	}
+
+	return nil
+}
+
+// Ping fails should Redis be unreachable or the consumer group not exist.
+func (c *Consumer) Ping(ctx context.Context) error {
+	groups, err := c.r.Provider().XInfoGroups(ctx, c.stream).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		if group.Name == c.group {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w", c.group, ErrGroupNotFound)
+}
+
+// Run handles every message delivered to the consumer until the context is done or the consumer is closed,
+// acknowledging each handled without error. Between reads, messages left pending elsewhere for longer than the duration
+// given by WithClaimMinIdle are claimed and handled in turn. Failing to claim or read is logged, and reading carries on
+// regardless, backing off whilst reads keep failing. Run returns nil once stopped, having finished the message being
+// handled, whilst the rest of its batch is left pending.
+func (c *Consumer) Run(ctx context.Context) error {
+	c.mu.Lock()
+	select {
+	case <-c.stop:
+		c.mu.Unlock()
+
+		return nil
+	default:
+	}
+
+	done := make(chan struct{})
+	c.done = done
+	c.mu.Unlock()
+
+	defer close(done)
+
+	err := c.CreateGroup(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var (
+		lastClaim time.Time
+		backoff   time.Duration
+	)
+
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= c.claimInterval {
+			err = c.claim(ctx)
+			if err != nil && ctx.Err() == nil {
+				c.logger.Error(fmt.Sprintf("%s-stream failed to claim pending messages", c.stream), zap.Error(err))
+			}
+
+			lastClaim = time.Now()
+		}
+
+		err = c.read(ctx)
+		if err == nil || ctx.Err() != nil {
+			backoff = 0
+
+			continue
+		}
+
+		backoff = nextBackoff(backoff)
+
+		c.logger.Error(fmt.Sprintf("%s-stream failed to read messages", c.stream),
+			zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+	}
+
+	return nil
+}
+
+// Close stops Run, waiting for the message being handled to be finished. Closing a consumer which is not running stops
+// it from running later.
+func (c *Consumer) Close() error {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+
+	return nil
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return minReadBackoff
+	}
+
+	backoff *= 2
+	if backoff > maxReadBackoff {
+		return maxReadBackoff
+	}
+
+	return backoff
+}
+
+// read handles the next batch of new messages, waiting up to the duration given by WithBlock for them to arrive.
+func (c *Consumer) read(ctx context.Context) error {
+	streams, err := c.r.Provider().XReadGroup(ctx, &goredis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.consumer,
+		Streams:  []string{c.stream, ">"},
+		Count:    c.batchSize,
+		Block:    c.block,
+	}).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, s := range streams {
+		c.handle(ctx, s.Messages, resultHandled)
+	}
+
+	return nil
+}
+
+// claim takes every message which has been pending elsewhere for too long, such as on a consumer which crashed, and
+// handles it. XAUTOCLAIM is sent as is, rather than via the client's XAutoClaim, which only understands the reply of
+// Redis 6.2 and not the deleted IDs Redis 7 adds to it.
+func (c *Consumer) claim(ctx context.Context) error {
+	start := claimDone
+
+	for {
+		reply, err := c.r.Provider().Do(ctx, "XAUTOCLAIM", c.stream, c.group, c.consumer,
+			c.claimMinIdle.Milliseconds(), start, "COUNT", c.batchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		next, messages, err := parseClaim(reply)
+		if err != nil {
+			return err
+		}
+
+		c.handle(ctx, c.deadLetter(ctx, messages), resultClaimed)
+
+		if next == claimDone || next == "" {
+			return nil
+		}
+
+		start = next
+	}
+}
+
+// parseClaim reads the cursor and messages of an XAUTOCLAIM reply, ignoring the deleted IDs which follow them from
+// Redis 7 onwards. Entries deleted whilst pending are nil in the replies of Redis 6.2, and are skipped.
+func parseClaim(reply interface{}) (string, []goredis.XMessage, error) {
+	parts, ok := reply.([]interface{})
+	if !ok || len(parts) < 2 {
+		return "", nil, fmt.Errorf("%v: %w", reply, ErrInvalidClaimReply)
+	}
+
+	next, ok := parts[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("cursor %v: %w", parts[0], ErrInvalidClaimReply)
+	}
+
+	entries, ok := parts[1].([]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("entries %v: %w", parts[1], ErrInvalidClaimReply)
+	}
+
+	messages := make([]goredis.XMessage, 0, len(entries))
+
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue
+		}
+
+		id, ok := entry[0].(string)
+		if !ok {
+			return "", nil, fmt.Errorf("id %v: %w", entry[0], ErrInvalidClaimReply)
+		}
+
+		fields, _ := entry[1].([]interface{})
+		values := make(map[string]interface{}, len(fields)/2)
+
+		for i := 0; i+1 < len(fields); i += 2 {
+			key, ok := fields[i].(string)
+			if !ok {
+				return "", nil, fmt.Errorf("field %v: %w", fields[i], ErrInvalidClaimReply)
+			}
+
+			values[key] = fields[i+1]
+		}
+
+		messages = append(messages, goredis.XMessage{ID: id, Values: values})
+	}
+
+	return next, messages, nil
+}
+
+// deadLetter moves each claimed message which has been delivered more times than the limit given by
+// WithMaxDeliveries to the dead letter stream, returning the rest to be handled. Should its delivery count not be
+// read, a message is handled regardless.
+func (c *Consumer) deadLetter(ctx context.Context, messages []goredis.XMessage) []goredis.XMessage {
+	if c.maxDeliveries <= 0 {
+		return messages
+	}
+
+	remaining := make([]goredis.XMessage, 0, len(messages))
+
+	for _, m := range messages {
+		pending, err := c.r.Provider().XPendingExt(ctx, &goredis.XPendingExtArgs{
+			Stream:   c.stream,
+			Group:    c.group,
+			Start:    m.ID,
+			End:      m.ID,
+			Count:    1,
+			Consumer: c.consumer,
+		}).Result()
+		if err != nil {
+			c.logger.Error(fmt.Sprintf("%s-stream failed to read delivery count", c.stream),
+				zap.String("id", m.ID), zap.Error(err))
+		}
+
+		if err != nil || len(pending) == 0 || pending[0].RetryCount <= c.maxDeliveries {
+			remaining = append(remaining, m)
+
+			continue
+		}
+
+		err = c.moveToDeadLetter(ctx, m)
+		if err != nil {
+			c.logger.Error(fmt.Sprintf("%s-stream failed to dead letter message", c.stream),
+				zap.String("id", m.ID), zap.Error(err))
+
+			continue
+		}
+
+		messageCount.WithLabelValues(c.stream, c.group, resultDeadLettered).Inc()
+		c.logger.Warn(fmt.Sprintf("%s-stream dead lettered message", c.stream), zap.String("id", m.ID),
+			zap.Int64("deliveries", pending[0].RetryCount), zap.String("dead_letter_stream", c.deadLetterStream))
+	}
+
+	return remaining
+}
+
+// moveToDeadLetter adds the message to the dead letter stream before acknowledging it, so that it is never lost, though
+// it may be added twice should the acknowledgement fail.
+func (c *Consumer) moveToDeadLetter(ctx context.Context, m goredis.XMessage) error {
+	err := c.r.Provider().XAdd(ctx, &goredis.XAddArgs{
+		Stream: c.deadLetterStream,
+		Values: m.Values,
+	}).Err()
+	if err != nil {
+		return err
+	}
+
+	return c.r.Provider().XAck(ctx, c.stream, c.group, m.ID).Err()
+}
+
+// handle handles each message in turn, acknowledging those handled without error. Once the context is done, the
+// message being handled is finished, as the handler and acknowledgement are not cancelled along with it, whilst the
+// rest are left pending.
+func (c *Consumer) handle(ctx context.Context, messages []goredis.XMessage, result string) {
+	handleCtx := detached.WithoutCancel(ctx)
+
+	for _, m := range messages {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := c.handler(handleCtx, Message{ID: m.ID, Values: m.Values})
+		if err != nil {
+			messageCount.WithLabelValues(c.stream, c.group, resultFailed).Inc()
+			c.logger.Error(fmt.Sprintf("%s-stream failed to handle message", c.stream),
+				zap.String("id", m.ID), zap.Error(err))
+
+			continue
+		}
+
+		err = c.r.Provider().XAck(handleCtx, c.stream, c.group, m.ID).Err()
+		if err != nil {
+			messageCount.WithLabelValues(c.stream, c.group, resultFailed).Inc()
+			c.logger.Error(fmt.Sprintf("%s-stream failed to acknowledge message", c.stream),
+				zap.String("id", m.ID), zap.Error(err))
+
+			continue
+		}
+
+		messageCount.WithLabelValues(c.stream, c.group, result).Inc()
+	}
+}
@@ -0,0 +1,406 @@
+package stream_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jamieaitken/cgs/redis"
+	"github.com/jamieaitken/cgs/stream"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenOpts        []stream.Option
+		expectedConsumer string
+	}{
+		{
+			name:             "given a consumer name, expect it to be used",
+			givenOpts:        []stream.Option{stream.WithConsumerName("worker-1")},
+			expectedConsumer: "worker-1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := redis.New([]string{"127.0.0.1:1"}, redis.WithClientType(redis.NonFailOver))
+
+			defer r.Close()
+
+			c := stream.New(r, "events", "workers", handle, test.givenOpts...)
+
+			if !cmp.Equal(c.Consumer(), test.expectedConsumer) {
+				t.Fatal(cmp.Diff(c.Consumer(), test.expectedConsumer))
+			}
+		})
+	}
+}
+
+func TestConsumer_Run_Fail(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedError error
+	}{
+		{
+			name:          "given an unreachable redis, expect the group to fail to be created",
+			expectedError: stream.ErrFailedToCreateGroup,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := redis.New([]string{"127.0.0.1:1"}, redis.WithClientType(redis.NonFailOver))
+
+			defer r.Close()
+
+			c := stream.New(r, "events", "workers", handle)
+
+			err := c.Run(context.Background())
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestConsumer_Run(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenReplies     map[string][]string
+		expectedHandled  []string
+		expectedCommands map[string]int
+	}{
+		{
+			name: "given a pending message claimed from redis 7, expect it to be handled",
+			givenReplies: map[string][]string{
+				"XAUTOCLAIM": {
+					"*3\r\n$3\r\n0-0\r\n*1\r\n*2\r\n$3\r\n1-0\r\n*2\r\n$5\r\ntitle\r\n$4\r\nDune\r\n*1\r\n$3\r\n2-0\r\n",
+					emptyClaim,
+				},
+				"XPENDING": {"*1\r\n*4\r\n$3\r\n1-0\r\n$7\r\nworkers\r\n:60000\r\n:2\r\n"},
+			},
+			expectedHandled:  []string{"1-0"},
+			expectedCommands: map[string]int{"XACK events workers 1-0": 1},
+		},
+		{
+			name: "given claiming fails, expect new messages to be read regardless",
+			givenReplies: map[string][]string{
+				"XAUTOCLAIM": {"-ERR unknown command 'XAUTOCLAIM'\r\n"},
+				"XREADGROUP": {
+					"*1\r\n*2\r\n$6\r\nevents\r\n*1\r\n*2\r\n$3\r\n3-0\r\n*2\r\n$5\r\ntitle\r\n$4\r\nEmma\r\n",
+					noMessages,
+				},
+			},
+			expectedHandled:  []string{"3-0"},
+			expectedCommands: map[string]int{"XACK events workers 3-0": 1},
+		},
+		{
+			name: "given a message delivered too many times, expect it to be dead lettered rather than handled",
+			givenReplies: map[string][]string{
+				"XAUTOCLAIM": {
+					"*2\r\n$3\r\n0-0\r\n*1\r\n*2\r\n$3\r\n4-0\r\n*2\r\n$5\r\ntitle\r\n$4\r\nLoki\r\n",
+					emptyClaim,
+				},
+				"XPENDING": {"*1\r\n*4\r\n$3\r\n4-0\r\n$7\r\nworkers\r\n:60000\r\n:6\r\n"},
+			},
+			expectedCommands: map[string]int{
+				"XADD events:dead-letter * title Loki": 1,
+				"XACK events workers 4-0":              1,
+			},
+		},
+		{
+			name: "given reads which fail, expect reading to be retried until the context is done",
+			givenReplies: map[string][]string{
+				"XREADGROUP": {"-LOADING Redis is loading the dataset in memory\r\n"},
+			},
+			expectedCommands: map[string]int{"XREADGROUP": 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr, commands := listenRedis(t, test.givenReplies)
+
+			r := redis.New([]string{addr}, redis.WithClientType(redis.NonFailOver))
+
+			defer r.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+			defer cancel()
+
+			var (
+				mu     sync.Mutex
+				actual []string
+			)
+
+			c := stream.New(r, "events", "workers", func(ctx context.Context, msg stream.Message) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				actual = append(actual, msg.ID)
+
+				return nil
+			}, stream.WithConsumerName("workers-1"))
+
+			err := c.Run(ctx)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if !cmp.Equal(actual, test.expectedHandled, cmpopts.EquateEmpty()) {
+				t.Fatal(cmp.Diff(actual, test.expectedHandled, cmpopts.EquateEmpty()))
+			}
+
+			sent := commands()
+
+			for prefix, expected := range test.expectedCommands {
+				count := 0
+
+				for _, command := range sent {
+					if strings.HasPrefix(command, prefix) {
+						count++
+					}
+				}
+
+				if count < expected {
+					t.Fatalf("expected %s to be sent at least %d times, got %d in %v", prefix, expected, count, sent)
+				}
+			}
+		})
+	}
+}
+
+func TestConsumer_Close(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenReplies     map[string][]string
+		expectedCommands []string
+	}{
+		{
+			name: "given a message being handled, expect it finished and acknowledged before close returns",
+			givenReplies: map[string][]string{
+				"XREADGROUP": {
+					"*1\r\n*2\r\n$6\r\nevents\r\n*2\r\n" +
+						"*2\r\n$3\r\n5-0\r\n*2\r\n$5\r\ntitle\r\n$4\r\nEmma\r\n" +
+						"*2\r\n$3\r\n6-0\r\n*2\r\n$5\r\ntitle\r\n$4\r\nDune\r\n",
+					noMessages,
+				},
+			},
+			expectedCommands: []string{"XACK events workers 5-0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr, commands := listenRedis(t, test.givenReplies)
+
+			r := redis.New([]string{addr}, redis.WithClientType(redis.NonFailOver))
+
+			defer r.Close()
+
+			handling := make(chan struct{})
+			release := make(chan struct{})
+
+			c := stream.New(r, "events", "workers", func(ctx context.Context, msg stream.Message) error {
+				close(handling)
+				<-release
+
+				return ctx.Err()
+			})
+
+			ran := make(chan error)
+
+			go func() {
+				ran <- c.Run(context.Background())
+			}()
+
+			<-handling
+
+			closed := make(chan error)
+
+			go func() {
+				closed <- c.Close()
+			}()
+
+			select {
+			case <-closed:
+				t.Fatal("expected close to wait for the message being handled")
+			case <-time.After(time.Millisecond * 100):
+			}
+
+			close(release)
+
+			err := <-closed
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			err = <-ran
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			var acks []string
+
+			for _, command := range commands() {
+				if strings.HasPrefix(command, "XACK") {
+					acks = append(acks, command)
+				}
+			}
+
+			if !cmp.Equal(acks, test.expectedCommands) {
+				t.Fatal(cmp.Diff(acks, test.expectedCommands))
+			}
+		})
+	}
+}
+
+func handle(ctx context.Context, msg stream.Message) error {
+	return nil
+}
+
+const (
+	emptyClaim = "*2\r\n$3\r\n0-0\r\n*0\r\n"
+	noMessages = "*-1\r\n"
+)
+
+// defaultReplies answer the commands of a consumer with nothing to do.
+var defaultReplies = map[string]string{
+	"XGROUP":     "+OK\r\n",
+	"XAUTOCLAIM": emptyClaim,
+	"XREADGROUP": noMessages,
+	"XPENDING":   "*0\r\n",
+	"XACK":       ":1\r\n",
+	"XADD":       "$3\r\n9-0\r\n",
+}
+
+// listenRedis listens as a Redis server answering each command with the next reply given for its name, the last of
+// which is repeated, or else its default reply. The returned func lists every command received, in order.
+func listenRedis(t *testing.T, replies map[string][]string) (string, func() []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+
+	next := func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		name := strings.ToUpper(args[0])
+
+		received = append(received, strings.Join(append([]string{name}, args[1:]...), " "))
+
+		queued, ok := replies[name]
+		if !ok || len(queued) == 0 {
+			reply, ok := defaultReplies[name]
+			if !ok {
+				return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+			}
+
+			return reply
+		}
+
+		if len(queued) > 1 {
+			replies[name] = queued[1:]
+		}
+
+		return queued[0]
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				rd := bufio.NewReader(conn)
+
+				for {
+					args, err := readCommand(rd)
+					if err != nil {
+						return
+					}
+
+					reply := next(args)
+
+					// A read which finds nothing blocks for a while, as Redis would, rather than the consumer spinning.
+					if reply == noMessages {
+						time.Sleep(time.Millisecond * 50)
+					}
+
+					_, err = conn.Write([]byte(reply))
+					if err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), received...)
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+
+	for i := range args {
+		line, err = rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		b := make([]byte, size+2)
+
+		_, err = io.ReadFull(rd, b)
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = string(b[:size])
+	}
+
+	return args, nil
+}
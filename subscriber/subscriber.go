@@ -90,3 +90,7 @@ func (k *KafkaSubscriber) Ping(ctx context.Context) error {
 
 	return nil
 }
+
+func (k *KafkaSubscriber) Close() error {
+	return k.client.Close(k.topic)
+}
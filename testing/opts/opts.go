@@ -3,6 +3,7 @@ package opts
 import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/jamieaitken/cgs/mysql"
+	"github.com/jamieaitken/cgs/postgres"
 	"github.com/jamieaitken/cgs/publisher"
 	"github.com/jamieaitken/cgs/redis"
 	"github.com/jamieaitken/cgs/server"
@@ -17,17 +18,28 @@ var KafkaClientComparer = cmp.Comparer(func(x, y *kafka.Client) bool {
 
 var RedisComparer = cmp.Comparer(func(x, y redis.Redis) bool {
 	return x.DB() == y.DB() && cmp.Equal(x.Addrs(), y.Addrs()) &&
-		x.Password() == y.Password() && x.MasterName() == y.MasterName()
+		x.Password() == y.Password() && x.MasterName() == y.MasterName() &&
+		x.Username() == y.Username() && x.SentinelPassword() == y.SentinelPassword() &&
+		x.PoolSize() == y.PoolSize() && x.MinIdleConns() == y.MinIdleConns() &&
+		x.DialTimeout() == y.DialTimeout() && x.ReadTimeout() == y.ReadTimeout() &&
+		x.WriteTimeout() == y.WriteTimeout() && x.MaxRetries() == y.MaxRetries() &&
+		(x.TLSConfig() == nil) == (y.TLSConfig() == nil)
 })
 
 var SQLComparer = cmp.Comparer(func(x, y mysql.MySQL) bool {
+	return x.MaxIdleCons() == y.MaxIdleCons() && x.MaxOpenCons() == y.MaxOpenCons() &&
+		x.MaxLifetime() == y.MaxLifetime() && x.Addr() == y.Addr() &&
+		x.SlowQueryThreshold() == y.SlowQueryThreshold()
+})
+
+var PostgresComparer = cmp.Comparer(func(x, y postgres.Postgres) bool {
 	return x.MaxIdleCons() == y.MaxIdleCons() && x.MaxOpenCons() == y.MaxOpenCons() &&
 		x.MaxLifetime() == y.MaxLifetime() && x.Addr() == y.Addr()
 })
 
 var ServerComparer = cmp.Comparer(func(x, y server.Server) bool {
 	return x.ReadTimeout() == y.ReadTimeout() && x.WriteTimeout() == y.WriteTimeout() &&
-		x.Addr() == y.Addr()
+		x.Addr() == y.Addr() && x.AdminAddr() == y.AdminAddr()
 })
 
 var SubscriberComparer = cmp.Comparer(func(x, y subscriber.KafkaSubscriber) bool {